@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"ClamGuardian/config"
+	"ClamGuardian/internal/matcher"
+	"ClamGuardian/internal/monitor"
+	"ClamGuardian/internal/position"
+	"ClamGuardian/internal/reader"
+	"ClamGuardian/internal/source"
+	"ClamGuardian/internal/syslog"
+)
+
+// newSources 按配置创建所有输入源
+func newSources(cfg *config.Config, pm *position.Manager, m *matcher.Matcher) ([]source.Source, error) {
+	var sources []source.Source
+	for _, in := range cfg.Inputs {
+		src, err := newSource(cfg, in, pm, m)
+		if err != nil {
+			for _, s := range sources {
+				s.Stop()
+			}
+			return nil, fmt.Errorf("创建输入 %s 失败: %v", in.Name, err)
+		}
+		sources = append(sources, src)
+	}
+	return sources, nil
+}
+
+// newSource 按输入类型创建输入源
+func newSource(cfg *config.Config, in config.InputConfig, pm *position.Manager, m *matcher.Matcher) (source.Source, error) {
+	switch in.Type {
+	case source.KindFile:
+		return monitor.NewMonitor(monitor.Options{
+			Name:         in.Name,
+			Paths:        in.Paths,
+			Patterns:     in.Patterns,
+			Exclude:      in.Exclude,
+			ExcludeRegex: in.ExcludeRegex,
+			Lines:        lineOptions(cfg),
+			Backend:      in.Backend,
+			PollInterval: time.Duration(in.PollInterval) * time.Second,
+			Workers:      in.Workers,
+			PathHandler:  pathHandler(m),
+		}, pm)
+	case source.KindStdin:
+		return source.NewStdin(in.Name, lineOptions(cfg)), nil
+	case source.KindSyslog:
+		return syslog.NewServer(in.Name, in.Listeners)
+	}
+	return nil, fmt.Errorf("不支持的输入类型: %s", in.Type)
+}
+
+// lineOptions 按行读取的配置，单行最大长度为读取缓冲区大小
+func lineOptions(cfg *config.Config) reader.LineOptions {
+	return reader.LineOptions{
+		MaxLineSize:    cfg.System.BufferSize,
+		LongLines:      cfg.System.LongLines,
+		PartialTimeout: time.Duration(cfg.System.PartialLineTimeout) * time.Second,
+	}
+}
+
+// pathHandler 为配置了专用规则或规则标签的监控路径创建独立的匹配器
+// 专用规则在前，按标签选择的全局规则在后，白名单和阈值规则与全局匹配器共享
+func pathHandler(m *matcher.Matcher) func(p monitor.PathConfig) (source.Handler, error) {
+	return func(p monitor.PathConfig) (source.Handler, error) {
+		pathMatcher, err := m.WithRules(p.Rules, p.RuleTags)
+		if err != nil {
+			return nil, err
+		}
+		return pathMatcher, nil
+	}
+}
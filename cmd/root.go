@@ -14,7 +14,6 @@ import (
 	"ClamGuardian/internal/logger"
 	"ClamGuardian/internal/matcher"
 	"ClamGuardian/internal/metrics"
-	"ClamGuardian/internal/monitor"
 	"ClamGuardian/internal/position"
 	"ClamGuardian/internal/status"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -134,7 +133,7 @@ func run(cmd *cobra.Command, args []string) error {
 
 	// 加载配置后立即更新状态管理器
 	stateManager := metrics.GetStateManager()
-	stateManager.SetConfig(viper.ConfigFileUsed(), cfg.Monitor.Paths)
+	stateManager.SetConfig(viper.ConfigFileUsed(), cfg.MonitorPaths())
 
 	// 创建 PID 文件
 	if err := writePID(cfg.System.PidFile); err != nil {
@@ -159,7 +158,7 @@ func run(cmd *cobra.Command, args []string) error {
 	// 现在可以安全地使用 logger
 	logger.Logger.Info("应用启动",
 		zap.String("config_file", viper.ConfigFileUsed()),
-		zap.Strings("monitor_paths", cfg.Monitor.Paths),
+		zap.Strings("monitor_paths", cfg.MonitorPaths()),
 		zap.Strings("patterns", cfg.Monitor.Patterns),
 		zap.Any("rules", cfg.Matcher.Rules))
 
@@ -170,15 +169,15 @@ func run(cmd *cobra.Command, args []string) error {
 	}
 
 	// 创建匹配器
-	m, err := matcher.NewMatcher(cfg.Matcher.Rules, cfg.System.BufferSize)
+	m, err := matcher.NewMatcher(cfg.Matcher)
 	if err != nil {
 		return fmt.Errorf("创建匹配器失败: %v", err)
 	}
 
-	// 创建监控器
-	mon, err := monitor.NewMonitor(cfg.Monitor.Paths, cfg.Monitor.Patterns, m, pm, cfg.System.BufferSize)
+	// 创建所有输入源
+	sources, err := newSources(cfg, pm, m)
 	if err != nil {
-		return fmt.Errorf("创建监控器失败: %v", err)
+		return err
 	}
 
 	// 启动内存监控
@@ -188,9 +187,18 @@ func run(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// 启动文件监控
-	if err := mon.Start(ctx); err != nil {
-		return fmt.Errorf("启动监控失败: %v", err)
+	// 检查心跳规则
+	go m.Run(ctx)
+
+	// 启动所有输入源，事件使用相同的规则匹配
+	for _, src := range sources {
+		if err := src.Start(ctx, m); err != nil {
+			return fmt.Errorf("启动输入 %s 失败: %v", src.Name(), err)
+		}
+		defer src.Stop()
+		logger.Logger.Info("输入已启动",
+			zap.String("input", src.Name()),
+			zap.String("type", src.Kind()))
 	}
 
 	// 启动状态监控
@@ -209,7 +217,13 @@ func run(cmd *cobra.Command, args []string) error {
 	if cfg.Metrics.Enabled {
 		http.Handle(cfg.Metrics.Path, promhttp.Handler())
 		// 添加文件状态端点
-		http.Handle("/files", metrics.FileStatusHandler(pm))
+		var reporters []metrics.SkipReporter
+		for _, src := range sources {
+			if r, ok := src.(metrics.SkipReporter); ok {
+				reporters = append(reporters, r)
+			}
+		}
+		http.Handle("/files", metrics.FileStatusHandler(pm, m, reporters...))
 
 		go func() {
 			addr := fmt.Sprintf(":%d", cfg.Metrics.Port)
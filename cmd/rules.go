@@ -0,0 +1,361 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ClamGuardian/config"
+	"ClamGuardian/internal/matcher"
+	"ClamGuardian/internal/monitor"
+	"ClamGuardian/internal/parser"
+	"ClamGuardian/internal/source"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	// rules test 配置
+	testRulesFile string
+	testFixtures  string
+	testParser    string
+	testFields    map[string]string
+	testEval      string
+	testPath      string
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "匹配规则工具",
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:   "test [文件...]",
+	Short: "用示例内容测试匹配规则",
+	Long: `逐行读取文件（未指定文件或文件为 "-" 时读取标准输入），输出每行命中的规则 ID、级别和提取的字段。
+默认使用配置文件中的规则（包括 rules_dir 和白名单），--rules-file 指定规则文件或规则目录时只使用其中的规则。
+指定 --path 时使用该文件所属监控路径的 rules 和 rule_tags 选择的规则，与运行时该文件使用的规则相同。
+指定 --fixtures 时按用例文件检查结果，有用例不通过时以非零状态退出，可以在 CI 中测试规则。`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          runRulesTest,
+}
+
+func init() {
+	rulesTestCmd.Flags().StringVar(&testRulesFile, "rules-file", "", "规则文件或规则目录，格式与 rules_dir 中的文件相同")
+	rulesTestCmd.Flags().StringVar(&testFixtures, "fixtures", "", "用例文件，列出每行内容预期命中的规则")
+	rulesTestCmd.Flags().StringVar(&testParser, "parser", "", "解析每行内容的解析器，例如 clamd")
+	rulesTestCmd.Flags().StringVar(&testEval, "evaluation", "", "覆盖规则求值方式: all、first-match 或 highest-severity")
+	rulesTestCmd.Flags().StringToStringVar(&testFields, "field", nil, "附加到每行内容的字段，例如 path=/var/log/clamav/clamd.log")
+	rulesTestCmd.Flags().StringVar(&testPath, "path", "", "按配置中该文件所属的监控路径选择规则和解析器，并作为 path 字段")
+
+	rulesCmd.AddCommand(rulesTestCmd)
+	rootCmd.AddCommand(rulesCmd)
+}
+
+// ruleFixtures 用例文件的内容
+type ruleFixtures struct {
+	Parser string        `mapstructure:"parser"` // 未设置时使用 --parser
+	Cases  []fixtureCase `mapstructure:"cases"`
+}
+
+// fixtureCase 单个用例：一行内容及预期按顺序命中的规则，expect 为空表示不应命中任何规则
+type fixtureCase struct {
+	Name   string            `mapstructure:"name"`
+	Line   string            `mapstructure:"line"`
+	Fields map[string]string `mapstructure:"fields"` // 来源附带的字段，例如 path
+	Expect []fixtureExpect   `mapstructure:"expect"`
+}
+
+// fixtureExpect 预期命中的规则，level 和 fields 只检查设置了的部分
+type fixtureExpect struct {
+	Rule       string            `mapstructure:"rule"`
+	Level      string            `mapstructure:"level"`
+	Fields     map[string]string `mapstructure:"fields"`
+	Suppressed bool              `mapstructure:"suppressed"`
+}
+
+func runRulesTest(cmd *cobra.Command, args []string) error {
+	m, err := testMatcher()
+	if err != nil {
+		return err
+	}
+
+	if testFixtures != "" {
+		return runFixtures(m, testFixtures)
+	}
+
+	p, err := parser.New(testParser)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+
+	total, matched := 0, 0
+	for _, name := range args {
+		err := eachLine(name, func(n int, line string) {
+			total++
+			results := m.Evaluate(line, lineFields(p, line, nil))
+			if len(results) == 0 {
+				return
+			}
+			matched++
+			fmt.Printf("%s:%d: %s\n", name, n, line)
+			for _, r := range results {
+				printResult(r)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("\n共 %d 行，%d 行命中规则\n", total, matched)
+	return nil
+}
+
+// testMatcher 按 --rules-file 或配置文件创建匹配器，指定 --path 时返回该路径使用的匹配器
+func testMatcher() (*matcher.Matcher, error) {
+	var cfg *config.Config
+	if testRulesFile == "" || testPath != "" {
+		var err error
+		if cfg, err = config.LoadConfig(); err != nil {
+			return nil, fmt.Errorf("加载配置失败: %v", err)
+		}
+	}
+
+	var (
+		m   *matcher.Matcher
+		err error
+	)
+	if testRulesFile == "" {
+		if testEval != "" {
+			cfg.Matcher.Evaluation = testEval
+		}
+		m, err = matcher.NewMatcher(cfg.Matcher)
+	} else {
+		m, err = rulesFileMatcher(testRulesFile)
+	}
+	if err != nil || testPath == "" {
+		return m, err
+	}
+	return pathMatcher(cfg, m, testPath)
+}
+
+// pathMatcher 按文件所属监控路径的 rules 和 rule_tags 创建匹配器，与运行时相同
+// 未指定 --parser 时使用该路径配置的解析器；未通过 --field 指定时，以该文件和所属输入作为 path 和 input 字段
+func pathMatcher(cfg *config.Config, m *matcher.Matcher, name string) (*matcher.Matcher, error) {
+	name, err := filepath.Abs(name)
+	if err != nil {
+		return nil, fmt.Errorf("无效的路径: %v", err)
+	}
+	input, p := findPath(cfg, name)
+	if p == nil {
+		return nil, fmt.Errorf("%s 不在任何文件输入的监控路径下", name)
+	}
+
+	if testParser == "" {
+		testParser = p.Parser
+	}
+	if testFields == nil {
+		testFields = make(map[string]string)
+	}
+	for key, value := range map[string]string{"path": name, "input": input} {
+		if _, ok := testFields[key]; !ok {
+			testFields[key] = value
+		}
+	}
+
+	if !p.HasRules() {
+		return m, nil
+	}
+	return m.WithRules(p.Rules, p.RuleTags)
+}
+
+// findPath 查找文件所属的输入和监控路径，多个匹配时取最长的根目录，与监控器相同
+func findPath(cfg *config.Config, name string) (string, *monitor.PathConfig) {
+	var (
+		input string
+		found *monitor.PathConfig
+	)
+	for i := range cfg.Inputs {
+		in := &cfg.Inputs[i]
+		if in.Type != source.KindFile {
+			continue
+		}
+		for j := range in.Paths {
+			p := &in.Paths[j]
+			if p.Contains(name) && (found == nil || len(filepath.Clean(p.Path)) > len(filepath.Clean(found.Path))) {
+				input, found = in.Name, p
+			}
+		}
+	}
+	return input, found
+}
+
+// rulesFileMatcher 只使用规则文件或规则目录中的规则创建匹配器
+func rulesFileMatcher(path string) (*matcher.Matcher, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取规则失败: %v", err)
+	}
+	var rules []matcher.MatchRule
+	if info.IsDir() {
+		rules, err = matcher.LoadRulesDir(path)
+	} else {
+		var file *matcher.RuleFile
+		if file, err = matcher.LoadRuleFile(path); err == nil {
+			rules = file.Rules
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return matcher.NewMatcher(matcher.Config{Rules: rules, Evaluation: testEval})
+}
+
+// runFixtures 检查用例文件中的每个用例，有用例不通过时返回错误
+func runFixtures(m *matcher.Matcher, path string) error {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("读取用例文件失败: %v", err)
+	}
+	var fixtures ruleFixtures
+	if err := v.Unmarshal(&fixtures); err != nil {
+		return fmt.Errorf("解析用例文件失败: %v", err)
+	}
+	if fixtures.Parser == "" {
+		fixtures.Parser = testParser
+	}
+	p, err := parser.New(fixtures.Parser)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i, c := range fixtures.Cases {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		results := m.Evaluate(c.Line, lineFields(p, c.Line, c.Fields))
+		problems := checkExpect(c.Expect, results)
+		if len(problems) == 0 {
+			fmt.Printf("PASS %s\n", name)
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL %s\n  内容: %s\n", name, c.Line)
+		for _, problem := range problems {
+			fmt.Printf("  %s\n", problem)
+		}
+		for _, r := range results {
+			printResult(r)
+		}
+	}
+
+	fmt.Printf("\n共 %d 个用例，通过 %d 个，失败 %d 个\n", len(fixtures.Cases), len(fixtures.Cases)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d 个用例未通过", failed)
+	}
+	return nil
+}
+
+// checkExpect 比较预期与实际命中的规则，返回不一致之处
+func checkExpect(expect []fixtureExpect, results []matcher.Result) []string {
+	var problems []string
+	if len(expect) != len(results) {
+		want := make([]string, len(expect))
+		for i, e := range expect {
+			want[i] = e.Rule
+		}
+		got := make([]string, len(results))
+		for i, r := range results {
+			got[i] = r.RuleID
+		}
+		return append(problems, fmt.Sprintf("预期命中 %v，实际命中 %v", want, got))
+	}
+	for i, e := range expect {
+		r := results[i]
+		if e.Rule != r.RuleID {
+			problems = append(problems, fmt.Sprintf("第 %d 条预期命中 %s，实际命中 %s", i+1, e.Rule, r.RuleID))
+			continue
+		}
+		if e.Level != "" && e.Level != r.Level {
+			problems = append(problems, fmt.Sprintf("规则 %s 预期级别 %s，实际级别 %s", r.RuleID, e.Level, r.Level))
+		}
+		if e.Suppressed != r.Suppressed {
+			problems = append(problems, fmt.Sprintf("规则 %s 预期抑制 %v，实际抑制 %v", r.RuleID, e.Suppressed, r.Suppressed))
+		}
+		for _, key := range matcher.SortedKeys(e.Fields) {
+			if value, ok := r.Fields[key]; !ok || value != e.Fields[key] {
+				problems = append(problems, fmt.Sprintf("规则 %s 的字段 %s 预期为 %q，实际为 %q", r.RuleID, key, e.Fields[key], value))
+			}
+		}
+	}
+	return problems
+}
+
+// lineFields 合并 --field 指定的字段、用例的字段和解析器输出的字段
+func lineFields(p parser.Parser, line string, fields map[string]string) map[string]string {
+	merged := make(map[string]string, len(testFields)+len(fields))
+	for key, value := range testFields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	if p != nil {
+		for key, value := range p.Parse(line) {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// eachLine 逐行读取文件，name 为 "-" 时读取标准输入
+func eachLine(name string, fn func(n int, line string)) error {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("打开文件失败: %v", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	n := 0
+	for scanner.Scan() {
+		n++
+		fn(n, strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取 %s 失败: %v", name, err)
+	}
+	return nil
+}
+
+// printResult 输出一条规则的命中结果
+func printResult(r matcher.Result) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "  [%s] 级别: %s", r.RuleID, r.Level)
+	if r.Name != "" {
+		fmt.Fprintf(&b, " 名称: %s", r.Name)
+	}
+	if r.Suppressed {
+		fmt.Fprintf(&b, " (被白名单抑制: %s)", r.Reason)
+	}
+	for _, key := range matcher.SortedKeys(r.Fields) {
+		fmt.Fprintf(&b, " %s=%s", key, r.Fields[key])
+	}
+	fmt.Println(b.String())
+}
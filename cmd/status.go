@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
 	"ClamGuardian/config"
+	"ClamGuardian/internal/matcher"
 	"ClamGuardian/internal/metrics"
 	"github.com/spf13/cobra"
 )
@@ -65,16 +68,52 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	fmt.Printf("最大备份数: %d\n", cfg.Log.MaxBackups)
 	fmt.Printf("保留天数: %d\n", cfg.Log.MaxAge)
 
-	// 匹配规则
+	// 匹配规则，包括规则目录中的规则
 	fmt.Println("\n=== 匹配规则 ===")
-	if len(cfg.Matcher.Rules) == 0 {
+	if cfg.Matcher.RulesDir != "" {
+		fmt.Printf("规则目录: %s\n", cfg.Matcher.RulesDir)
+	}
+	evaluation := cfg.Matcher.Evaluation
+	if evaluation == "" {
+		evaluation = matcher.EvaluationAll
+	}
+	fmt.Printf("求值方式: %s\n", evaluation)
+	rules, err := cfg.Matcher.AllRules()
+	if err != nil {
+		return fmt.Errorf("读取规则失败: %v", err)
+	}
+	if len(rules) == 0 {
 		fmt.Println("未配置匹配规则")
 	} else {
-		for i, rule := range cfg.Matcher.Rules {
-			fmt.Printf("%d. 模式: %s, 级别: %s\n", i+1, rule.Pattern, rule.Level)
+		for i, rule := range rules {
+			fmt.Printf("%d. [%s] 模式: %s, 级别: %s\n", i+1, rule.RuleID(), rule.Pattern, rule.Level)
+			if rule.Name != "" {
+				fmt.Printf("   名称: %s\n", rule.Name)
+			}
+			if rule.Description != "" {
+				fmt.Printf("   说明: %s\n", rule.Description)
+			}
+			if len(rule.Tags) > 0 {
+				fmt.Printf("   标签: %s\n", strings.Join(rule.Tags, ", "))
+			}
+			if rule.Priority != 0 || rule.Stop {
+				fmt.Printf("   优先级: %d, 命中后停止: %v\n", rule.Priority, rule.Stop)
+			}
 		}
 	}
 
+	// 心跳规则
+	fmt.Println("\n=== 心跳规则 ===")
+	if len(cfg.Matcher.Heartbeats) == 0 {
+		fmt.Println("未配置心跳规则")
+	} else {
+		for i, hb := range cfg.Matcher.Heartbeats {
+			fmt.Printf("%d. 名称: %s, 模式: %s, 路径: %s, 间隔: %d秒, 级别: %s\n",
+				i+1, hb.Name, hb.Pattern, hb.Path, hb.Interval, hb.Level)
+		}
+		printHeartbeats(cfg)
+	}
+
 	// 系统状态
 	memory, cpu := stateManager.GetSystemMetrics()
 	fmt.Println("\n=== 系统状态 ===")
@@ -121,6 +160,47 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// printHeartbeats 从运行中实例的 /files 端点读取并打印各文件的心跳状态
+func printHeartbeats(cfg *config.Config) {
+	if !cfg.Metrics.Enabled {
+		fmt.Println("指标未启用，无法获取心跳状态")
+		return
+	}
+
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/files", cfg.Metrics.Port))
+	if err != nil {
+		fmt.Printf("获取心跳状态失败: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var files []struct {
+		Filename   string                    `json:"filename"`
+		Heartbeats []metrics.HeartbeatStatus `json:"heartbeats"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		fmt.Printf("解析心跳状态失败: %v\n", err)
+		return
+	}
+
+	fmt.Printf("\n%-50s %-20s %-10s %-20s\n", "文件名", "心跳规则", "状态", "最后出现时间")
+	fmt.Println(strings.Repeat("-", 100))
+	for _, file := range files {
+		filename := file.Filename
+		if len(filename) > 50 {
+			filename = "..." + filename[len(filename)-47:]
+		}
+		for _, hb := range file.Heartbeats {
+			lastSeen := "-"
+			if hb.LastSeen != nil {
+				lastSeen = hb.LastSeen.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-50s %-20s %-10s %-20s\n", filename, hb.Rule, hb.State, lastSeen)
+		}
+	}
+}
+
 // formatBytes 格式化字节数，支持 uint64 和 int64
 func formatBytes(bytes interface{}) string {
 	var bytesInt uint64
@@ -2,27 +2,63 @@ package config
 
 import (
 	"fmt"
+	"reflect"
 
+	"ClamGuardian/internal/glob"
 	"ClamGuardian/internal/matcher"
+	"ClamGuardian/internal/monitor"
+	"ClamGuardian/internal/parser"
+	"ClamGuardian/internal/reader"
+	"ClamGuardian/internal/source"
+	"ClamGuardian/internal/syslog"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
+// 输入名称，未使用 inputs 配置时 monitor 和 syslog 部分分别作为这两个输入
+const (
+	DefaultFileInput   = "files"
+	DefaultSyslogInput = "syslog"
+)
+
+// FileInputConfig 文件输入配置
+type FileInputConfig struct {
+	Paths        []monitor.PathConfig `mapstructure:"paths"` // 支持字符串或对象两种写法
+	Patterns     []string             `mapstructure:"patterns"`
+	Exclude      []string             `mapstructure:"exclude"`       // 排除的文件模式，对所有监控路径生效
+	ExcludeRegex []string             `mapstructure:"exclude_regex"` // 排除的完整路径正则
+	StartAt      string               `mapstructure:"start_at"`      // 默认的启动读取策略
+	Backend      string               `mapstructure:"backend"`       // 监控后端：inotify、poll 或 auto
+	PollInterval int                  `mapstructure:"poll_interval"` // 轮询间隔(秒)
+	Workers      int                  `mapstructure:"workers"`       // 并行处理文件的 worker 数，未设置时使用监控器的默认值
+}
+
+// InputConfig 命名的输入配置，按 type 使用对应的字段
+type InputConfig struct {
+	Name            string `mapstructure:"name"`
+	Type            string `mapstructure:"type"` // file、stdin 或 syslog
+	FileInputConfig `mapstructure:",squash"`
+	Listeners       []syslog.ListenerConfig `mapstructure:"listeners"` // syslog 输入的监听地址
+}
+
 type Config struct {
-	Monitor struct {
-		Paths    []string `mapstructure:"paths"`
-		Patterns []string `mapstructure:"patterns"`
-	} `mapstructure:"monitor"`
-	Matcher struct {
-		Rules []matcher.MatchRule `mapstructure:"rules"`
-	} `mapstructure:"matcher"`
+	Monitor FileInputConfig `mapstructure:"monitor"`
+	Inputs  []InputConfig   `mapstructure:"inputs"` // 加载后包含 monitor 和 syslog 部分转换而来的输入
+	Matcher matcher.Config  `mapstructure:"matcher"`
+	Syslog  struct {
+		Enabled   bool                    `mapstructure:"enabled"`
+		Listeners []syslog.ListenerConfig `mapstructure:"listeners"`
+	} `mapstructure:"syslog"`
 	Position struct {
 		StorePath      string `mapstructure:"store_path"`
 		UpdateInterval int    `mapstructure:"update_interval"`
 	} `mapstructure:"position"`
 	System struct {
-		MemoryLimit int64  `mapstructure:"memory_limit"`
-		BufferSize  int    `mapstructure:"buffer_size"`
-		PidFile     string `mapstructure:"pid_file"` // 新增：PID文件路径
+		MemoryLimit        int64  `mapstructure:"memory_limit"`
+		BufferSize         int    `mapstructure:"buffer_size"`
+		LongLines          string `mapstructure:"long_lines"`           // 超过 buffer_size 的行：truncate、split 或 skip
+		PartialLineTimeout int    `mapstructure:"partial_line_timeout"` // 文件末尾未写完的行最长等待时间(秒)
+		PidFile            string `mapstructure:"pid_file"`             // 新增：PID文件路径
 	} `mapstructure:"system"`
 	Metrics struct {
 		Enabled bool   `mapstructure:"enabled"`
@@ -45,7 +81,12 @@ type Config struct {
 // LoadConfig 加载配置文件
 func LoadConfig() (*Config, error) {
 	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
+	hook := viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		stringToPathConfigHook,
+	))
+	if err := viper.Unmarshal(&config, hook); err != nil {
 		return nil, fmt.Errorf("无法解析配置: %v", err)
 	}
 
@@ -54,10 +95,197 @@ func LoadConfig() (*Config, error) {
 		config.System.PidFile = "/var/run/clamguardian.pid"
 	}
 
+	if config.System.LongLines == "" {
+		config.System.LongLines = reader.LongLineTruncate
+	}
+	if !reader.ValidLongLine(config.System.LongLines) {
+		return nil, fmt.Errorf("无效的超长行策略: %s", config.System.LongLines)
+	}
+
 	// 验证必要的配置
-	if len(config.Monitor.Paths) == 0 {
-		return nil, fmt.Errorf("未指定监控路径")
+	if config.Monitor.Backend == "" {
+		config.Monitor.Backend = monitor.BackendInotify
+	}
+	if config.Monitor.PollInterval <= 0 {
+		config.Monitor.PollInterval = 1
+	}
+	if config.Monitor.StartAt == "" {
+		config.Monitor.StartAt = monitor.StartAtBeginning
+	}
+	if config.Syslog.Enabled && len(config.Syslog.Listeners) == 0 {
+		return nil, fmt.Errorf("已启用 syslog 但未配置监听地址")
+	}
+
+	// monitor 和 syslog 部分转换为命名输入，放在 inputs 之前
+	var inputs []InputConfig
+	if len(config.Monitor.Paths) > 0 {
+		inputs = append(inputs, InputConfig{
+			Name:            DefaultFileInput,
+			Type:            source.KindFile,
+			FileInputConfig: config.Monitor,
+		})
+	}
+	if config.Syslog.Enabled {
+		inputs = append(inputs, InputConfig{
+			Name:      DefaultSyslogInput,
+			Type:      source.KindSyslog,
+			Listeners: config.Syslog.Listeners,
+		})
+	}
+	config.Inputs = append(inputs, config.Inputs...)
+	if len(config.Inputs) == 0 {
+		return nil, fmt.Errorf("未指定监控路径或输入")
+	}
+
+	// 全局规则包括规则目录中的规则，监控路径的规则标签从中选择
+	rules, err := config.Matcher.AllRules()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for i := range config.Inputs {
+		in := &config.Inputs[i]
+		if in.Name == "" {
+			return nil, fmt.Errorf("第 %d 个输入未指定 name", i+1)
+		}
+		if names[in.Name] {
+			return nil, fmt.Errorf("输入名称重复: %s", in.Name)
+		}
+		names[in.Name] = true
+		if err := config.validateInput(in, rules); err != nil {
+			return nil, err
+		}
+	}
+
+	// 检查规则，包括各监控路径的专用规则
+	var pathRules [][]matcher.MatchRule
+	for _, in := range config.Inputs {
+		for _, p := range in.Paths {
+			pathRules = append(pathRules, p.Rules)
+		}
+	}
+	if err := config.Matcher.Validate(pathRules...); err != nil {
+		return nil, err
 	}
 
 	return &config, nil
 }
+
+// validateInput 检查输入配置，未设置的文件输入选项继承 monitor 部分的值
+// rules 为全局规则，用于检查监控路径的规则标签
+func (c *Config) validateInput(in *InputConfig, rules []matcher.MatchRule) error {
+	switch in.Type {
+	case source.KindFile:
+	case source.KindStdin:
+		return nil
+	case source.KindSyslog:
+		if len(in.Listeners) == 0 {
+			return fmt.Errorf("syslog 输入 %s 未配置监听地址", in.Name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("输入 %s 的类型无效: %s", in.Name, in.Type)
+	}
+
+	f := &in.FileInputConfig
+	if len(f.Paths) == 0 {
+		return fmt.Errorf("文件输入 %s 未指定监控路径", in.Name)
+	}
+	if f.Backend == "" {
+		f.Backend = c.Monitor.Backend
+	}
+	if !monitor.ValidBackend(f.Backend) {
+		return fmt.Errorf("无效的监控后端: %s", f.Backend)
+	}
+	if f.PollInterval <= 0 {
+		f.PollInterval = c.Monitor.PollInterval
+	}
+	if f.Workers <= 0 {
+		f.Workers = c.Monitor.Workers
+	}
+	if f.StartAt == "" {
+		f.StartAt = c.Monitor.StartAt
+	}
+	if !monitor.ValidStartAt(f.StartAt) {
+		return fmt.Errorf("无效的 start_at: %s", f.StartAt)
+	}
+	if len(f.Patterns) == 0 {
+		f.Patterns = c.Monitor.Patterns
+	}
+	if len(f.Exclude) == 0 {
+		f.Exclude = c.Monitor.Exclude
+	}
+	if len(f.ExcludeRegex) == 0 {
+		f.ExcludeRegex = c.Monitor.ExcludeRegex
+	}
+	if err := validGlobs(f.Patterns); err != nil {
+		return err
+	}
+	if err := validGlobs(f.Exclude); err != nil {
+		return err
+	}
+	for i := range f.Paths {
+		p := &f.Paths[i]
+		if p.Path == "" {
+			return fmt.Errorf("输入 %s 的第 %d 个监控路径未指定 path", in.Name, i+1)
+		}
+		if err := validGlobs(p.Include); err != nil {
+			return err
+		}
+		if err := validGlobs(p.Exclude); err != nil {
+			return err
+		}
+		if err := matcher.CheckRuleTags(rules, p.RuleTags); err != nil {
+			return fmt.Errorf("监控路径 %s: %v", p.Path, err)
+		}
+		if p.MaxDepth < 0 {
+			return fmt.Errorf("监控路径 %s 的 max_depth 不能为负数", p.Path)
+		}
+		if p.StartAt == "" {
+			p.StartAt = f.StartAt
+		}
+		if !monitor.ValidStartAt(p.StartAt) {
+			return fmt.Errorf("监控路径 %s 的 start_at 无效: %s", p.Path, p.StartAt)
+		}
+		if !parser.Valid(p.Parser) {
+			return fmt.Errorf("监控路径 %s 的解析器无效: %s", p.Path, p.Parser)
+		}
+		if !reader.ValidEncoding(p.Encoding) {
+			return fmt.Errorf("监控路径 %s 的字符编码无效: %s", p.Path, p.Encoding)
+		}
+	}
+	return nil
+}
+
+// validGlobs 检查文件模式的写法
+func validGlobs(patterns []string) error {
+	for _, pattern := range patterns {
+		if err := glob.Valid(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MonitorPaths 返回所有文件输入的监控路径
+func (c *Config) MonitorPaths() []string {
+	var paths []string
+	for _, in := range c.Inputs {
+		if in.Type != source.KindFile {
+			continue
+		}
+		for _, p := range in.Paths {
+			paths = append(paths, p.Path)
+		}
+	}
+	return paths
+}
+
+// stringToPathConfigHook 允许监控路径直接写成字符串
+func stringToPathConfigHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(monitor.PathConfig{}) {
+		return data, nil
+	}
+	return monitor.PathConfig{Path: data.(string)}, nil
+}
@@ -0,0 +1,83 @@
+package glob
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Valid 检查模式的写法是否正确
+func Valid(pattern string) error {
+	for _, segment := range splitSegments(filepath.ToSlash(pattern)) {
+		if _, err := filepath.Match(segment, ""); err != nil {
+			return fmt.Errorf("无效的文件模式 %s: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Match 判断路径是否匹配模式
+// 模式中不含路径分隔符时只匹配文件名；
+// 绝对路径模式匹配完整路径，其余模式匹配相对于根目录 root 的路径。
+// "**" 可匹配零个或多个目录层级。
+func Match(pattern, root, name string) bool {
+	pattern = filepath.ToSlash(pattern)
+	if !strings.Contains(pattern, "/") {
+		match, _ := filepath.Match(pattern, filepath.Base(name))
+		return match
+	}
+
+	target := filepath.ToSlash(name)
+	if !strings.HasPrefix(pattern, "/") {
+		rel, err := filepath.Rel(root, name)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return false
+		}
+		target = filepath.ToSlash(rel)
+	}
+
+	return matchSegments(splitSegments(pattern), splitSegments(target))
+}
+
+// matchSegments 按路径段逐段匹配
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// 连续的 "**" 等价于一个
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if match, _ := filepath.Match(pattern[0], name[0]); !match {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
+
+// splitSegments 拆分路径段，忽略空段
+func splitSegments(p string) []string {
+	parts := strings.Split(p, "/")
+	segments := parts[:0]
+	for _, part := range parts {
+		if part != "" && part != "." {
+			segments = append(segments, part)
+		}
+	}
+	return segments
+}
@@ -0,0 +1,72 @@
+package matcher
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alert 规则命中后产生的告警
+type Alert struct {
+	Level   string
+	Content string            // 匹配到的完整内容
+	Fields  map[string]string // 来源附带的字段和命名捕获组提取的字段，捕获组优先
+}
+
+// DedupConfig 告警去重配置
+type DedupConfig struct {
+	Fields []string `mapstructure:"fields"` // 判断重复的字段，未设置时比较完整内容
+	Window int      `mapstructure:"window"` // 去重时间窗口(秒)，0 表示不去重
+}
+
+// maxDedupEntries 去重记录的最大数量，超过后不再记录新的告警（即不去重）
+const maxDedupEntries = 10000
+
+// dedupCache 记录时间窗口内已经发出的告警
+type dedupCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time // 告警的键及窗口结束时间
+}
+
+// newDedupCache 创建去重记录
+func newDedupCache() *dedupCache {
+	return &dedupCache{seen: make(map[string]time.Time)}
+}
+
+// duplicate 判断告警是否在窗口内已经发出过，没有时记录下来
+func (c *dedupCache) duplicate(key string, window time.Duration, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if until, ok := c.seen[key]; ok && now.Before(until) {
+		return true
+	}
+	if len(c.seen) >= maxDedupEntries {
+		for k, until := range c.seen {
+			if !now.Before(until) {
+				delete(c.seen, k)
+			}
+		}
+		if len(c.seen) >= maxDedupEntries {
+			return false
+		}
+	}
+	c.seen[key] = now.Add(window)
+	return false
+}
+
+// dedupKey 计算告警的去重键，包含规则 ID 以免不同规则互相影响，重新加载规则后去重状态仍然有效
+func dedupKey(id string, fields []string, alert Alert) string {
+	var b strings.Builder
+	b.WriteString(id)
+	if len(fields) == 0 {
+		b.WriteByte(0)
+		b.WriteString(alert.Content)
+		return b.String()
+	}
+	for _, field := range fields {
+		b.WriteByte(0)
+		b.WriteString(alert.Fields[field])
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package matcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"ClamGuardian/internal/glob"
+)
+
+// AllowEntry 白名单条目，用于已知的误报，设置的条件全部满足时告警被抑制
+type AllowEntry struct {
+	Signature string `mapstructure:"signature"` // 病毒特征名，支持 * 和 ? 通配符
+	Path      string `mapstructure:"path"`      // 被扫描文件的模式，写法与 patterns 相同，含 "/" 时必须是绝对路径
+	Regex     string `mapstructure:"regex"`     // 匹配告警的完整内容
+	Hash      string `mapstructure:"hash"`      // 文件的 MD5
+	Expires   string `mapstructure:"expires"`   // 过期时间，例如 2026-12-31（当天结束时过期）或 RFC 3339 时间
+	Reason    string `mapstructure:"reason"`    // 说明，记录在日志中
+}
+
+// allowEntry 编译后的白名单条目
+type allowEntry struct {
+	AllowEntry
+	regex   *regexp.Regexp
+	expires time.Time // 零值表示不过期
+}
+
+// compileAllowlist 检查并编译白名单
+func compileAllowlist(entries []AllowEntry) ([]allowEntry, error) {
+	compiled := make([]allowEntry, 0, len(entries))
+	for i, e := range entries {
+		entry := allowEntry{AllowEntry: e}
+		if e.Signature == "" && e.Path == "" && e.Regex == "" && e.Hash == "" {
+			return nil, fmt.Errorf("白名单第 %d 项未设置任何条件", i+1)
+		}
+		if e.Signature != "" {
+			if _, err := filepath.Match(e.Signature, ""); err != nil {
+				return nil, fmt.Errorf("白名单第 %d 项的特征名模式无效: %v", i+1, err)
+			}
+		}
+		if e.Path != "" {
+			if strings.Contains(e.Path, "/") && !filepath.IsAbs(e.Path) {
+				return nil, fmt.Errorf("白名单第 %d 项的路径模式必须是绝对路径: %s", i+1, e.Path)
+			}
+			if err := glob.Valid(e.Path); err != nil {
+				return nil, fmt.Errorf("白名单第 %d 项: %v", i+1, err)
+			}
+		}
+		if e.Regex != "" {
+			re, err := regexp.Compile(e.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("白名单第 %d 项的正则无效: %v", i+1, err)
+			}
+			entry.regex = re
+		}
+		if e.Expires != "" {
+			expires, err := parseExpires(e.Expires)
+			if err != nil {
+				return nil, fmt.Errorf("白名单第 %d 项的过期时间无效: %v", i+1, err)
+			}
+			entry.expires = expires
+		}
+		compiled = append(compiled, entry)
+	}
+	return compiled, nil
+}
+
+// parseExpires 解析过期时间，只写日期时在当天结束时过期
+func parseExpires(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// allows 判断告警是否被该条目抑制
+// 被扫描文件取 file 字段，自定义规则需要用命名捕获组 file 提取
+func (e *allowEntry) allows(alert Alert, now time.Time) bool {
+	if !e.expires.IsZero() && !now.Before(e.expires) {
+		return false
+	}
+	if e.Signature != "" {
+		if match, _ := filepath.Match(e.Signature, alert.Fields["signature"]); !match {
+			return false
+		}
+	}
+	if e.Path != "" {
+		file := alert.Fields["file"]
+		if file == "" || !glob.Match(e.Path, "", file) {
+			return false
+		}
+	}
+	if e.Hash != "" && !strings.EqualFold(e.Hash, alert.Fields["hash"]) {
+		return false
+	}
+	if e.regex != nil && !e.regex.MatchString(alert.Content) {
+		return false
+	}
+	return true
+}
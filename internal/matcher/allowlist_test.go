@@ -0,0 +1,56 @@
+package matcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllowEntryAllows(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.Local)
+	// 文件输入附带的 path 字段是日志文件本身的路径，不是被扫描文件
+	alert := Alert{
+		Content: "/srv/qa/eicar.com: Eicar-Test-Signature FOUND",
+		Fields: map[string]string{
+			"file":      "/srv/qa/eicar.com",
+			"signature": "Eicar-Test-Signature",
+			"path":      "/var/log/clamav/clamd.log",
+		},
+	}
+	noFile := Alert{
+		Content: "Eicar-Test-Signature FOUND",
+		Fields: map[string]string{
+			"signature": "Eicar-Test-Signature",
+			"path":      "/var/log/clamav/clamd.log",
+		},
+	}
+
+	tests := []struct {
+		name  string
+		entry AllowEntry
+		alert Alert
+		want  bool
+	}{
+		{"signature", AllowEntry{Signature: "Eicar-*"}, alert, true},
+		{"signature mismatch", AllowEntry{Signature: "Win.*"}, alert, false},
+		{"path", AllowEntry{Path: "/srv/qa/**"}, alert, true},
+		{"path mismatch", AllowEntry{Path: "/srv/uploads/**"}, alert, false},
+		{"path ignores log path", AllowEntry{Path: "/var/log/clamav/**"}, alert, false},
+		{"path without file", AllowEntry{Path: "/var/log/clamav/**"}, noFile, false},
+		{"regex", AllowEntry{Regex: `^/srv/qa/`}, alert, true},
+		{"all conditions", AllowEntry{Signature: "Eicar-*", Path: "/srv/qa/**", Regex: "FOUND$"}, alert, true},
+		{"one condition fails", AllowEntry{Signature: "Eicar-*", Path: "/srv/uploads/**"}, alert, false},
+		{"not expired", AllowEntry{Signature: "Eicar-*", Expires: "2026-06-01"}, alert, true},
+		{"expired", AllowEntry{Signature: "Eicar-*", Expires: "2026-05-31"}, alert, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compiled, err := compileAllowlist([]AllowEntry{tt.entry})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := compiled[0].allows(tt.alert, now); got != tt.want {
+				t.Errorf("allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Condition 规则的附加条件，在 pattern 匹配之后求值，可以组合多个正则、子串、字段相等和数值比较
+// 每个条件只能是 all、any、not 之一，或者是对单个字段的比较；
+// 比较的字段包括来源字段和命名捕获组提取的字段，未设置 field 时比较整行内容，字段不存在时比较结果为不满足
+type Condition struct {
+	All []Condition `mapstructure:"all"` // 全部满足
+	Any []Condition `mapstructure:"any"` // 任一满足
+	Not *Condition  `mapstructure:"not"` // 不满足
+
+	Field    string   `mapstructure:"field"`
+	Regex    string   `mapstructure:"regex"`    // 匹配正则
+	Contains string   `mapstructure:"contains"` // 包含子串
+	Equals   *string  `mapstructure:"equals"`   // 完全相等
+	GT       *float64 `mapstructure:"gt"`       // 数值比较，字段值不是数字时不满足，可以同时设置多个作为范围
+	GTE      *float64 `mapstructure:"gte"`
+	LT       *float64 `mapstructure:"lt"`
+	LTE      *float64 `mapstructure:"lte"`
+}
+
+// condition 编译后的条件
+type condition struct {
+	all, any []*condition
+	not      *condition
+
+	field    string
+	regex    *regexp.Regexp
+	contains string
+	equals   *string
+	numeric  bool
+	gt, gte  *float64
+	lt, lte  *float64
+}
+
+// compileCondition 检查并编译条件，path 为条件在规则中的位置，用于错误信息
+func compileCondition(c *Condition, path string) (*condition, error) {
+	kinds := 0
+	for _, set := range []bool{c.All != nil, c.Any != nil, c.Not != nil, c.isLeaf()} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, fmt.Errorf("条件 %s 必须是 all、any、not 之一或一个字段比较", path)
+	}
+
+	switch {
+	case c.All != nil, c.Any != nil:
+		list, name := c.All, "all"
+		if c.Any != nil {
+			list, name = c.Any, "any"
+		}
+		if len(list) == 0 {
+			return nil, fmt.Errorf("条件 %s.%s 不能为空", path, name)
+		}
+		compiled := make([]*condition, len(list))
+		for i := range list {
+			sub, err := compileCondition(&list[i], fmt.Sprintf("%s.%s[%d]", path, name, i))
+			if err != nil {
+				return nil, err
+			}
+			compiled[i] = sub
+		}
+		if c.All != nil {
+			return &condition{all: compiled}, nil
+		}
+		return &condition{any: compiled}, nil
+	case c.Not != nil:
+		sub, err := compileCondition(c.Not, path+".not")
+		if err != nil {
+			return nil, err
+		}
+		return &condition{not: sub}, nil
+	}
+
+	cond := &condition{
+		field:    c.Field,
+		contains: c.Contains,
+		equals:   c.Equals,
+		gt:       c.GT,
+		gte:      c.GTE,
+		lt:       c.LT,
+		lte:      c.LTE,
+		numeric:  c.GT != nil || c.GTE != nil || c.LT != nil || c.LTE != nil,
+	}
+	ops := 0
+	for _, set := range []bool{c.Regex != "", c.Contains != "", c.Equals != nil, cond.numeric} {
+		if set {
+			ops++
+		}
+	}
+	if ops != 1 {
+		return nil, fmt.Errorf("条件 %s 必须设置 regex、contains、equals 或数值比较 (gt、gte、lt、lte) 中的一种", path)
+	}
+	if cond.numeric && c.Field == "" {
+		return nil, fmt.Errorf("条件 %s 的数值比较必须指定 field", path)
+	}
+	if c.Regex != "" {
+		regex, err := regexp.Compile(c.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("条件 %s 的正则无效: %v", path, err)
+		}
+		cond.regex = regex
+	}
+	return cond, nil
+}
+
+// isLeaf 是否设置了字段比较
+func (c *Condition) isLeaf() bool {
+	return c.Field != "" || c.Regex != "" || c.Contains != "" || c.Equals != nil ||
+		c.GT != nil || c.GTE != nil || c.LT != nil || c.LTE != nil
+}
+
+// eval 对一行内容及其字段求值
+func (c *condition) eval(line string, fields map[string]string) bool {
+	switch {
+	case c.all != nil:
+		for _, sub := range c.all {
+			if !sub.eval(line, fields) {
+				return false
+			}
+		}
+		return true
+	case c.any != nil:
+		for _, sub := range c.any {
+			if sub.eval(line, fields) {
+				return true
+			}
+		}
+		return false
+	case c.not != nil:
+		return !c.not.eval(line, fields)
+	}
+
+	value := line
+	if c.field != "" {
+		var ok bool
+		if value, ok = fields[c.field]; !ok {
+			return false
+		}
+	}
+	switch {
+	case c.regex != nil:
+		return c.regex.MatchString(value)
+	case c.equals != nil:
+		return value == *c.equals
+	case c.numeric:
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return false
+		}
+		return (c.gt == nil || n > *c.gt) && (c.gte == nil || n >= *c.gte) &&
+			(c.lt == nil || n < *c.lt) && (c.lte == nil || n <= *c.lte)
+	}
+	return strings.Contains(value, c.contains)
+}
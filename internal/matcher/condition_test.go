@@ -0,0 +1,92 @@
+package matcher
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConditionEval(t *testing.T) {
+	str := func(s string) *string { return &s }
+	num := func(f float64) *float64 { return &f }
+
+	line := "/srv/uploads/a.exe: Win.Trojan.Agent-1 FOUND"
+	fields := map[string]string{
+		"file":      "/srv/uploads/a.exe",
+		"signature": "Win.Trojan.Agent-1",
+		"size":      " 2048 ",
+		"host":      "scan01",
+	}
+
+	tests := []struct {
+		name string
+		cond Condition
+		want bool
+	}{
+		{"regex on line", Condition{Regex: `FOUND$`}, true},
+		{"regex on field", Condition{Field: "file", Regex: `^/srv/uploads/`}, true},
+		{"regex mismatch", Condition{Field: "file", Regex: `^/home/`}, false},
+		{"contains", Condition{Contains: "Trojan"}, true},
+		{"contains field", Condition{Field: "host", Contains: "scan"}, true},
+		{"equals", Condition{Field: "host", Equals: str("scan01")}, true},
+		{"equals mismatch", Condition{Field: "host", Equals: str("scan")}, false},
+		{"equals empty field missing", Condition{Field: "missing", Equals: str("")}, false},
+		{"missing field", Condition{Field: "missing", Contains: "x"}, false},
+		{"gt", Condition{Field: "size", GT: num(1024)}, true},
+		{"gt boundary", Condition{Field: "size", GT: num(2048)}, false},
+		{"gte boundary", Condition{Field: "size", GTE: num(2048)}, true},
+		{"lt", Condition{Field: "size", LT: num(1024)}, false},
+		{"lte boundary", Condition{Field: "size", LTE: num(2048)}, true},
+		{"range", Condition{Field: "size", GT: num(1024), LT: num(4096)}, true},
+		{"range outside", Condition{Field: "size", GT: num(4096), LT: num(8192)}, false},
+		{"not a number", Condition{Field: "host", GT: num(0)}, false},
+		{"all", Condition{All: []Condition{{Contains: "FOUND"}, {Field: "host", Equals: str("scan01")}}}, true},
+		{"all one false", Condition{All: []Condition{{Contains: "FOUND"}, {Field: "host", Equals: str("x")}}}, false},
+		{"any", Condition{Any: []Condition{{Contains: "PUA"}, {Field: "size", GTE: num(1)}}}, true},
+		{"any none", Condition{Any: []Condition{{Contains: "PUA"}, {Field: "missing", Regex: "."}}}, false},
+		{"not", Condition{Not: &Condition{Field: "signature", Regex: `^PUA\.`}}, true},
+		{"not missing field", Condition{Not: &Condition{Field: "missing", Contains: "x"}}, true},
+		{"nested", Condition{All: []Condition{
+			{Field: "file", Regex: `^/srv/uploads/`},
+			{Not: &Condition{Field: "signature", Regex: `^PUA\.`}},
+			{Any: []Condition{{Field: "size", GTE: num(1048576)}, {Contains: "Trojan"}}},
+		}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := compileCondition(&tt.cond, "when")
+			if err != nil {
+				t.Fatalf("编译条件失败: %v", err)
+			}
+			if got := c.eval(line, fields); got != tt.want {
+				t.Errorf("eval = %v，应为 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileConditionErrors(t *testing.T) {
+	str := func(s string) *string { return &s }
+	num := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		cond Condition
+		want string // 错误信息应包含的内容
+	}{
+		{Condition{}, "条件 when 必须是"},
+		{Condition{All: []Condition{{Contains: "x"}}, Contains: "y"}, "条件 when 必须是"},
+		{Condition{All: []Condition{}}, "when.all 不能为空"},
+		{Condition{Any: []Condition{{Contains: "x"}, {}}}, "条件 when.any[1] 必须是"},
+		{Condition{Not: &Condition{Regex: "("}}, "条件 when.not 的正则无效"},
+		{Condition{Field: "host"}, "条件 when 必须设置"},
+		{Condition{Contains: "x", Equals: str("y")}, "条件 when 必须设置"},
+		{Condition{Regex: "x", GT: num(1)}, "条件 when 必须设置"},
+		{Condition{GT: num(1)}, "数值比较必须指定 field"},
+		{Condition{All: []Condition{{Not: &Condition{Field: "a", Contains: "b", LT: num(2)}}}}, "条件 when.all[0].not 必须设置"},
+	}
+	for _, tt := range tests {
+		_, err := compileCondition(&tt.cond, "when")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("compileCondition(%+v) 返回 %v，应包含 %q", tt.cond, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package matcher
+
+import "sort"
+
+// 规则的求值方式
+const (
+	EvaluationAll             = "all"              // 每条命中的规则都产生告警
+	EvaluationFirstMatch      = "first-match"      // 只有第一条命中的规则产生告警
+	EvaluationHighestSeverity = "highest-severity" // 只有级别最高的命中规则产生告警，级别相同时取排在前面的
+)
+
+// ValidEvaluation 检查求值方式是否有效，空字符串表示 all
+func ValidEvaluation(mode string) bool {
+	switch mode {
+	case "", EvaluationAll, EvaluationFirstMatch, EvaluationHighestSeverity:
+		return true
+	}
+	return false
+}
+
+// sortRules 按优先级从高到低排列规则，优先级相同时保持配置中的顺序
+func sortRules(rules []Rule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
+}
@@ -0,0 +1,235 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ClamGuardian/internal/glob"
+	"ClamGuardian/internal/logger"
+	"ClamGuardian/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// 心跳状态
+const (
+	HeartbeatPending = "pending" // 开始跟踪后尚未出现
+	HeartbeatOK      = "ok"      // 在间隔内出现过
+	HeartbeatMissing = "missing" // 超过间隔没有出现，已告警
+)
+
+// HeartbeatRule 心跳规则：每个文件中应当至少每隔 interval 秒出现一次匹配 pattern 的内容
+// 超时未出现时产生告警，再次出现时产生恢复事件
+type HeartbeatRule struct {
+	Name     string `mapstructure:"name"`
+	Pattern  string `mapstructure:"pattern"`
+	Path     string `mapstructure:"path"`     // 适用的文件，写法与 patterns 相同，含 "/" 时必须是绝对路径，未设置时适用于所有文件
+	Interval int    `mapstructure:"interval"` // 最长间隔(秒)
+	Level    string `mapstructure:"level"`    // 心跳丢失告警的级别
+}
+
+// beat 单个文件上的心跳状态
+type beat struct {
+	state string
+	since time.Time // 开始跟踪的时间
+	last  time.Time // 最近一次出现的时间，零值表示尚未出现
+}
+
+// heartbeat 心跳规则的运行状态
+type heartbeat struct {
+	rule     HeartbeatRule
+	pattern  *regexp.Regexp
+	interval time.Duration
+	beats    map[string]*beat // 按文件路径索引
+}
+
+// heartbeats 所有心跳规则，由同一配置创建的匹配器共享
+type heartbeats struct {
+	mu   sync.Mutex
+	list []*heartbeat
+}
+
+// newHeartbeats 检查并创建心跳规则
+// 不含通配符的路径在创建时就开始计时，文件一直没有内容时同样会告警
+func newHeartbeats(rules []HeartbeatRule, now time.Time) (*heartbeats, error) {
+	h := &heartbeats{}
+	names := make(map[string]bool)
+	for _, r := range rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("心跳规则未设置名称")
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("心跳规则名称重复: %s", r.Name)
+		}
+		names[r.Name] = true
+		if r.Interval < 1 {
+			return nil, fmt.Errorf("心跳规则 %s 的间隔必须大于 0", r.Name)
+		}
+		if !ValidSeverity(r.Level) {
+			return nil, fmt.Errorf("心跳规则 %s 的告警级别无效: %q", r.Name, r.Level)
+		}
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("心跳规则 %s 的正则无效: %v", r.Name, err)
+		}
+		if r.Path != "" {
+			if strings.Contains(r.Path, "/") && !filepath.IsAbs(r.Path) {
+				return nil, fmt.Errorf("心跳规则 %s 的路径必须是绝对路径: %s", r.Name, r.Path)
+			}
+			if err := glob.Valid(r.Path); err != nil {
+				return nil, fmt.Errorf("心跳规则 %s: %v", r.Name, err)
+			}
+		}
+
+		hb := &heartbeat{
+			rule:     r,
+			pattern:  pattern,
+			interval: time.Duration(r.Interval) * time.Second,
+			beats:    make(map[string]*beat),
+		}
+		if fixed := hb.fixedPath(); fixed != "" {
+			hb.beats[fixed] = &beat{state: HeartbeatPending, since: now}
+		}
+		h.list = append(h.list, hb)
+	}
+	return h, nil
+}
+
+// fixedPath 规则指定的不含通配符的绝对路径，其他写法返回空字符串
+func (hb *heartbeat) fixedPath() string {
+	if filepath.IsAbs(hb.rule.Path) && !strings.ContainsAny(hb.rule.Path, "*?[") {
+		return filepath.Clean(hb.rule.Path)
+	}
+	return ""
+}
+
+// observe 处理来自文件的一行内容，适用的文件在第一次出现内容时开始跟踪
+func (h *heartbeats) observe(path, line string, now time.Time) {
+	if path == "" || len(h.list) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, hb := range h.list {
+		b, ok := hb.beats[path]
+		if !ok {
+			if hb.rule.Path != "" && !glob.Match(hb.rule.Path, "", path) {
+				continue
+			}
+			b = &beat{state: HeartbeatPending, since: now}
+			hb.beats[path] = b
+		}
+		if !hb.pattern.MatchString(line) {
+			continue
+		}
+		if b.state == HeartbeatMissing {
+			metrics.HeartbeatMissing.WithLabelValues(hb.rule.Name, path).Set(0)
+			logger.Logger.Info("心跳恢复",
+				zap.String("heartbeat", hb.rule.Name),
+				zap.String("path", path),
+				zap.Duration("missing_for", now.Sub(b.deadline(hb.interval))))
+		}
+		b.state = HeartbeatOK
+		b.last = now
+	}
+}
+
+// forget 文件被删除或重命名后停止跟踪该路径，规则明确指定的文件仍继续计时
+func (h *heartbeats) forget(path string) {
+	if len(h.list) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, hb := range h.list {
+		if _, ok := hb.beats[path]; !ok || hb.fixedPath() == path {
+			continue
+		}
+		delete(hb.beats, path)
+		metrics.HeartbeatMissing.DeleteLabelValues(hb.rule.Name, path)
+	}
+}
+
+// deadline 下一次应当出现的最晚时间
+func (b *beat) deadline(interval time.Duration) time.Time {
+	if b.last.IsZero() {
+		return b.since.Add(interval)
+	}
+	return b.last.Add(interval)
+}
+
+// check 检查超时未出现的心跳并告警
+func (h *heartbeats) check(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, hb := range h.list {
+		for path, b := range hb.beats {
+			if b.state == HeartbeatMissing || now.Before(b.deadline(hb.interval)) {
+				continue
+			}
+			b.state = HeartbeatMissing
+			metrics.HeartbeatMissing.WithLabelValues(hb.rule.Name, path).Set(1)
+			logFields := []zap.Field{
+				zap.String("level", hb.rule.Level),
+				zap.String("heartbeat", hb.rule.Name),
+				zap.String("path", path),
+				zap.Int("interval", hb.rule.Interval),
+			}
+			if !b.last.IsZero() {
+				logFields = append(logFields, zap.Time("last_seen", b.last))
+			}
+			logger.Logger.Info("心跳丢失", logFields...)
+		}
+	}
+}
+
+// run 定期检查心跳，直到 ctx 结束
+func (h *heartbeats) run(ctx context.Context) {
+	if len(h.list) == 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			h.check(now)
+		}
+	}
+}
+
+// statuses 返回各文件的心跳状态
+func (h *heartbeats) statuses() map[string][]metrics.HeartbeatStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	result := make(map[string][]metrics.HeartbeatStatus)
+	for _, hb := range h.list {
+		for path, b := range hb.beats {
+			s := metrics.HeartbeatStatus{
+				Rule:     hb.rule.Name,
+				State:    b.state,
+				Interval: hb.rule.Interval,
+			}
+			if !b.last.IsZero() {
+				last := b.last
+				s.LastSeen = &last
+			}
+			result[path] = append(result[path], s)
+		}
+	}
+	for _, list := range result {
+		sort.Slice(list, func(i, j int) bool { return list[i].Rule < list[j].Rule })
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+package matcher
+
+import (
+	"testing"
+	"time"
+
+	"ClamGuardian/internal/source"
+)
+
+// beatState 返回心跳规则在文件上的状态，没有跟踪该文件时返回空字符串
+func beatState(h *heartbeats, path string) string {
+	for _, s := range h.statuses()[path] {
+		return s.State
+	}
+	return ""
+}
+
+func TestHeartbeatMissingAndRecovered(t *testing.T) {
+	start := time.Now()
+	h, err := newHeartbeats([]HeartbeatRule{{
+		Name:     "selfcheck",
+		Pattern:  "SelfCheck",
+		Path:     "*.log",
+		Interval: 60,
+		Level:    SeverityCritical,
+	}}, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const path = "/var/log/clamav/clamd.log"
+
+	h.observe(path, "Reading databases", start)
+	if state := beatState(h, path); state != HeartbeatPending {
+		t.Fatalf("第一次出现内容后状态为 %q，应为 %q", state, HeartbeatPending)
+	}
+	h.observe("/var/log/clamav/clamd.log.1", "SelfCheck", start)
+	if state := beatState(h, "/var/log/clamav/clamd.log.1"); state != "" {
+		t.Errorf("不匹配 path 的文件状态为 %q，不应跟踪", state)
+	}
+
+	h.observe(path, "SelfCheck: Database status OK", start.Add(30*time.Second))
+	h.check(start.Add(80 * time.Second))
+	if state := beatState(h, path); state != HeartbeatOK {
+		t.Fatalf("间隔内出现后状态为 %q，应为 %q", state, HeartbeatOK)
+	}
+
+	h.check(start.Add(91 * time.Second))
+	if state := beatState(h, path); state != HeartbeatMissing {
+		t.Fatalf("超过间隔后状态为 %q，应为 %q", state, HeartbeatMissing)
+	}
+
+	h.observe(path, "SelfCheck: Database status OK", start.Add(120*time.Second))
+	if state := beatState(h, path); state != HeartbeatOK {
+		t.Fatalf("再次出现后状态为 %q，应为 %q", state, HeartbeatOK)
+	}
+	h.check(start.Add(170 * time.Second))
+	if state := beatState(h, path); state != HeartbeatOK {
+		t.Errorf("恢复后从最近一次出现开始计时，状态为 %q，应为 %q", state, HeartbeatOK)
+	}
+}
+
+func TestHeartbeatForget(t *testing.T) {
+	start := time.Now()
+	const fixed = "/var/log/clamav/clamd.log"
+	h, err := newHeartbeats([]HeartbeatRule{
+		{Name: "any", Pattern: "SelfCheck", Interval: 60, Level: SeverityCritical},
+		{Name: "fixed", Pattern: "SelfCheck", Path: fixed, Interval: 60, Level: SeverityCritical},
+	}, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.observe("/var/log/app.log", "started", start)
+	h.check(start.Add(61 * time.Second))
+	if state := beatState(h, "/var/log/app.log"); state != HeartbeatMissing {
+		t.Fatalf("超过间隔后状态为 %q，应为 %q", state, HeartbeatMissing)
+	}
+
+	h.forget("/var/log/app.log")
+	if state := beatState(h, "/var/log/app.log"); state != "" {
+		t.Errorf("停止跟踪后状态为 %q，不应再检查", state)
+	}
+
+	// 规则明确指定的文件被删除后仍应告警
+	h.forget(fixed)
+	var rules []string
+	for _, s := range h.statuses()[fixed] {
+		rules = append(rules, s.Rule)
+	}
+	if len(rules) != 1 || rules[0] != "fixed" {
+		t.Errorf("停止跟踪后 %s 上的心跳规则为 %v，应只保留 fixed", fixed, rules)
+	}
+}
+
+func TestHandleBackfillSkipsHeartbeat(t *testing.T) {
+	m, err := NewMatcher(Config{Heartbeats: []HeartbeatRule{
+		{Name: "selfcheck", Pattern: "SelfCheck", Interval: 60, Level: SeverityCritical},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	origin := source.Origin{Input: "files", Kind: source.KindFile, Path: "/var/log/clamav/clamd.log.1.gz", Backfill: true}
+	m.Handle(source.Event{Line: "SelfCheck: Database status OK", Origin: origin})
+	if beats := m.Heartbeats(); len(beats) != 0 {
+		t.Errorf("归档中的内容不应计入心跳，状态为 %v", beats)
+	}
+
+	origin.Path, origin.Backfill = "/var/log/clamav/clamd.log", false
+	m.Handle(source.Event{Line: "SelfCheck: Database status OK", Origin: origin})
+	if state := beatState(m.heartbeats, origin.Path); state != HeartbeatOK {
+		t.Errorf("正在写入的文件中出现心跳后状态为 %q，应为 %q", state, HeartbeatOK)
+	}
+}
@@ -1,106 +1,591 @@
 package matcher
 
 import (
-	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"os"
 	"regexp"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"ClamGuardian/internal/logger"
 	"ClamGuardian/internal/metrics"
+	"ClamGuardian/internal/parser"
+	"ClamGuardian/internal/source"
 	"go.uber.org/zap"
 )
 
 // MatchRule 定义匹配规则的结构
+// Pattern 中的命名捕获组，例如 (?P<signature>\S+)，会作为字段记录到告警中
 type MatchRule struct {
-	Pattern string `mapstructure:"pattern"`
-	Level   string `mapstructure:"level"`
+	ID          string      `mapstructure:"id"`          // 规则的稳定标识，用于指标标签和告警日志，未设置时由规则内容生成
+	Name        string      `mapstructure:"name"`        // 规则名称
+	Description string      `mapstructure:"description"` // 规则说明
+	Pattern     string      `mapstructure:"pattern"`
+	Level       string      `mapstructure:"level"`    // 告警级别，取值见 Severity 常量
+	Field       string      `mapstructure:"field"`    // 匹配指定字段而不是整行内容，例如 syslog 的 app_name
+	Event       string      `mapstructure:"event"`    // 只匹配解析器识别出的该类型事件，例如 detection
+	Tags        []string    `mapstructure:"tags"`     // 规则标签，监控路径可以通过 rule_tags 选择规则
+	Labels      []string    `mapstructure:"labels"`   // 作为指标标签的字段，按字段值分别计数
+	Dedup       DedupConfig `mapstructure:"dedup"`    // 时间窗口内字段相同的告警只记录一次
+	Priority    int         `mapstructure:"priority"` // 优先级，数值大的规则先求值，相同时按配置顺序
+	Stop        bool        `mapstructure:"stop"`     // 命中后不再对这一行求值后面的规则
+	When        *Condition  `mapstructure:"when"`     // pattern 匹配后还需满足的条件
+}
+
+// ruleIDPattern 规则 ID 的写法
+var ruleIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]*$`)
+
+// RuleID 返回规则的 ID，未设置时由规则内容（级别、字段、事件类型、正则、条件、labels、dedup、priority 和 stop）生成，
+// 规则内容不变时 ID 不变；只设置了级别、字段、事件类型和正则的规则只按这几项生成
+func (r *MatchRule) RuleID() string {
+	if r.ID != "" {
+		return r.ID
+	}
+	content := r.Level + "\x00" + r.Field + "\x00" + r.Event + "\x00" + r.Pattern
+	if r.When != nil || len(r.Labels) > 0 || r.Dedup.Window != 0 || len(r.Dedup.Fields) > 0 || r.Priority != 0 || r.Stop {
+		extra, _ := json.Marshal(struct {
+			When     *Condition
+			Labels   []string
+			Dedup    DedupConfig
+			Priority int
+			Stop     bool
+		}{r.When, r.Labels, r.Dedup, r.Priority, r.Stop})
+		content += "\x00" + string(extra)
+	}
+	sum := sha1.Sum([]byte(content))
+	return "rule-" + hex.EncodeToString(sum[:4])
+}
+
+// duplicateIDError 规则 ID 重复的错误，生成的 ID 重复说明两条规则内容相同
+func duplicateIDError(r *MatchRule) error {
+	if r.ID == "" {
+		return fmt.Errorf("规则 %s 生成的 ID %s 与另一条规则重复，请为规则设置 id", r.Pattern, r.RuleID())
+	}
+	return fmt.Errorf("规则 ID 重复: %s", r.ID)
+}
+
+// Config 匹配器配置
+type Config struct {
+	Rules      []MatchRule     `mapstructure:"rules"`
+	Evaluation string          `mapstructure:"evaluation"` // 求值方式: all（默认）、first-match 或 highest-severity
+	RulesDir   string          `mapstructure:"rules_dir"`  // 规则文件目录，文件变化时重新加载
+	Allowlist  []AllowEntry    `mapstructure:"allowlist"`  // 已知误报的白名单，对所有规则生效
+	Thresholds []ThresholdRule `mapstructure:"thresholds"` // 基于单行规则命中的阈值规则
+	Heartbeats []HeartbeatRule `mapstructure:"heartbeats"` // 应当定期出现的内容
+}
+
+// SelectRules 返回带有任一指定标签的规则
+func SelectRules(rules []MatchRule, tags []string) []MatchRule {
+	var selected []MatchRule
+	for _, r := range rules {
+		if hasAnyTag(r.Tags, tags) {
+			selected = append(selected, r)
+		}
+	}
+	return selected
+}
+
+// CheckRuleTags 检查每个规则标签都至少选择了一条规则
+func CheckRuleTags(rules []MatchRule, tags []string) error {
+	for _, tag := range tags {
+		if len(SelectRules(rules, []string{tag})) == 0 {
+			return fmt.Errorf("规则标签 %s 没有对应的规则", tag)
+		}
+	}
+	return nil
+}
+
+// hasAnyTag 判断两组标签是否有交集
+func hasAnyTag(tags, wanted []string) bool {
+	for _, tag := range tags {
+		for _, w := range wanted {
+			if tag == w {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // Rule 内部使用的规则结构
 type Rule struct {
-	Pattern *regexp.Regexp
-	Level   string
+	ID       string
+	Name     string
+	Tags     []string
+	Pattern  *regexp.Regexp
+	Level    string
+	Field    string
+	Event    string
+	Labels   []string
+	Dedup    DedupConfig
+	Priority int
+	Stop     bool
+
+	when     *condition
+	captures bool // 是否包含命名捕获组
+	rank     int  // 告警级别的严重程度
+}
+
+// reservedFields 告警日志自身使用的字段和日志编码器的键，不能作为捕获组名称
+var reservedFields = map[string]bool{
+	"level": true, "content": true, "rule_id": true, "rule_name": true, "tags": true,
+	"time": true, "msg": true, "logger": true, "caller": true, "func": true, "stacktrace": true,
 }
 
 // Matcher 正则匹配器
 type Matcher struct {
-	rules      []Rule
-	bufferSize int
+	rules      []Rule     // 重新加载时整体替换，读取时使用 mu
+	filter     *prefilter // 与 rules 一起替换，为 nil 时每条规则都要匹配
+	evaluation string
 	matchCount int64
+	allowlist  []allowEntry
+	thresholds *thresholds
+	heartbeats *heartbeats
+	dedup      *dedupCache
 	mu         sync.RWMutex
+
+	// 根匹配器的规则来源，由 reloadMu 保护
+	reloadMu sync.Mutex
+	base     []MatchRule // config.yaml 中的规则
+	rulesDir string
+	global   []MatchRule // 当前生效的全局规则
+	derived  []*Matcher  // WithRules 创建的匹配器，重新加载时一并更新
+
+	// WithRules 创建的匹配器的规则来源
+	pathRules []MatchRule
+	ruleTags  []string
 }
 
 // NewMatcher 创建新的匹配器
-func NewMatcher(rules []MatchRule, bufferSize int) (*Matcher, error) {
+func NewMatcher(cfg Config) (*Matcher, error) {
+	if !ValidEvaluation(cfg.Evaluation) {
+		return nil, fmt.Errorf("无效的规则求值方式: %s", cfg.Evaluation)
+	}
+	allowlist, err := compileAllowlist(cfg.Allowlist)
+	if err != nil {
+		return nil, err
+	}
+	thresholds, err := newThresholds(cfg.Thresholds)
+	if err != nil {
+		return nil, err
+	}
+	heartbeats, err := newHeartbeats(cfg.Heartbeats, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	global, err := cfg.AllRules()
+	if err != nil {
+		return nil, err
+	}
+	rules, err := compileRules(global)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Matcher{
+		evaluation: cfg.Evaluation,
+		allowlist:  allowlist,
+		thresholds: thresholds,
+		heartbeats: heartbeats,
+		dedup:      newDedupCache(),
+		base:       cfg.Rules,
+		rulesDir:   cfg.RulesDir,
+		global:     global,
+	}
+	m.setRules(rules)
+	return m, nil
+}
+
+// WithRules 创建使用另一组规则的匹配器：rules 在前，全局规则中带有任一 tags 标签的规则在后
+// 白名单、阈值规则和心跳规则的状态与原匹配器共享，原匹配器重新加载规则时按新的全局规则更新
+func (m *Matcher) WithRules(rules []MatchRule, tags []string) (*Matcher, error) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	d := &Matcher{
+		evaluation: m.evaluation,
+		allowlist:  m.allowlist,
+		thresholds: m.thresholds,
+		heartbeats: m.heartbeats,
+		dedup:      newDedupCache(),
+		pathRules:  rules,
+		ruleTags:   tags,
+	}
+	compiled, err := compileRules(d.selectRules(m.global))
+	if err != nil {
+		return nil, err
+	}
+	d.setRules(compiled)
+	m.derived = append(m.derived, d)
+	return d, nil
+}
+
+// selectRules 返回 WithRules 创建的匹配器在给定全局规则下使用的规则
+func (m *Matcher) selectRules(global []MatchRule) []MatchRule {
+	rules := append([]MatchRule{}, m.pathRules...)
+	return append(rules, SelectRules(global, m.ruleTags)...)
+}
+
+// Run 定期检查心跳规则并监视规则目录，直到 ctx 结束
+func (m *Matcher) Run(ctx context.Context) {
+	if m.rulesDir != "" {
+		go m.watchRules(ctx)
+	}
+	m.heartbeats.run(ctx)
+}
+
+// Heartbeats 返回各文件的心跳规则状态
+func (m *Matcher) Heartbeats() map[string][]metrics.HeartbeatStatus {
+	return m.heartbeats.statuses()
+}
+
+// Validate 检查匹配器配置，包括规则目录中的规则，pathRules 为各监控路径的专用规则
+func (c *Config) Validate(pathRules ...[]MatchRule) error {
+	if !ValidEvaluation(c.Evaluation) {
+		return fmt.Errorf("无效的规则求值方式: %s", c.Evaluation)
+	}
+	global, err := c.AllRules()
+	if err != nil {
+		return err
+	}
+	sets := append([][]MatchRule{global}, pathRules...)
+	for _, rules := range sets {
+		if _, err := compileRules(rules); err != nil {
+			return err
+		}
+	}
+	if err := checkRuleIDs(sets); err != nil {
+		return err
+	}
+	if _, err := compileAllowlist(c.Allowlist); err != nil {
+		return err
+	}
+	if _, err := newThresholds(c.Thresholds); err != nil {
+		return err
+	}
+	if _, err := newHeartbeats(c.Heartbeats, time.Now()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// compileRules 编译单行规则
+func compileRules(rules []MatchRule) ([]Rule, error) {
 	var compiledRules []Rule
+	ids := make(map[string]bool)
 	for _, r := range rules {
+		if ids[r.RuleID()] {
+			return nil, duplicateIDError(&r)
+		}
+		ids[r.RuleID()] = true
+		if r.ID != "" && !ruleIDPattern.MatchString(r.ID) {
+			return nil, fmt.Errorf("规则 ID 无效: %s，只能包含字母、数字和 _.:-", r.ID)
+		}
+		// 错误信息中的规则名称
+		ruleName := r.ID
+		if ruleName == "" {
+			ruleName = r.Pattern
+		}
+		if !ValidSeverity(r.Level) {
+			return nil, fmt.Errorf("规则 %s 的告警级别无效: %q，可选值: %s", ruleName, r.Level, strings.Join(severities, ", "))
+		}
 		pattern, err := regexp.Compile(r.Pattern)
 		if err != nil {
 			return nil, fmt.Errorf("编译正则表达式失败 %s: %v", r.Pattern, err)
 		}
+		captures := false
+		for _, name := range pattern.SubexpNames() {
+			if reservedFields[name] {
+				return nil, fmt.Errorf("规则 %s 的捕获组名称 %s 与告警日志字段冲突", r.Pattern, name)
+			}
+			captures = captures || name != ""
+		}
+		if r.Event != "" && !parser.ValidEvent(r.Event) {
+			return nil, fmt.Errorf("规则 %s 的事件类型无效: %s", r.Pattern, r.Event)
+		}
+		if r.Dedup.Window < 0 {
+			return nil, fmt.Errorf("规则 %s 的去重时间窗口不能为负数", r.Pattern)
+		}
+		var when *condition
+		if r.When != nil {
+			if when, err = compileCondition(r.When, "when"); err != nil {
+				return nil, fmt.Errorf("规则 %s: %v", ruleName, err)
+			}
+		}
 		compiledRules = append(compiledRules, Rule{
-			Pattern: pattern,
-			Level:   r.Level,
+			ID:       r.RuleID(),
+			Name:     r.Name,
+			Tags:     r.Tags,
+			Pattern:  pattern,
+			Level:    r.Level,
+			Field:    r.Field,
+			Event:    r.Event,
+			Labels:   r.Labels,
+			Dedup:    r.Dedup,
+			Priority: r.Priority,
+			Stop:     r.Stop,
+			when:     when,
+			captures: captures,
+			rank:     severityRank(r.Level),
 		})
 	}
+	sortRules(compiledRules)
+	return compiledRules, nil
+}
 
-	return &Matcher{
-		rules:      compiledRules,
-		bufferSize: bufferSize,
-	}, nil
+// GetMatchCount 获取总匹配次数
+func (m *Matcher) GetMatchCount() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.matchCount
 }
 
-// ProcessFile 处理文件内容
-func (m *Matcher) ProcessFile(filename string, offset int64) (int64, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return offset, fmt.Errorf("打开文件失败: %v", err)
+// Handle 处理输入源产生的事件，来源信息作为字段一并记录
+func (m *Matcher) Handle(ev source.Event) {
+	fields := make(map[string]string, len(ev.Fields)+2)
+	for key, value := range ev.Fields {
+		fields[key] = value
 	}
-	defer file.Close()
-
-	if _, err := file.Seek(offset, 0); err != nil {
-		return offset, fmt.Errorf("设置文件偏移量失败: %v", err)
+	fields["input"] = ev.Origin.Input
+	if ev.Origin.Path != "" {
+		fields["path"] = ev.Origin.Path
 	}
+	if ev.Origin.Backfill {
+		// 轮转后和归档文件中的内容不是新出现的，不计入心跳
+		m.matchRules(ev.Line, fields, m.report)
+		return
+	}
+	m.Match(ev.Line, fields)
+}
 
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, m.bufferSize)
-	scanner.Buffer(buf, m.bufferSize)
+// ClosePath 文件停止跟踪后不再检查该路径上的心跳
+func (m *Matcher) ClosePath(path string) {
+	m.heartbeats.forget(path)
+}
 
-	var newOffset int64
-	for scanner.Scan() {
-		line := scanner.Text()
-		m.matchLine(line)
-		newOffset, _ = file.Seek(0, 1)
+// Match 匹配一条内容，fields 为来源附带的字段（例如 syslog 头部），可为 nil
+// 指定了 Field 的规则匹配对应字段的值，字段同时记录到告警日志中
+func (m *Matcher) Match(line string, fields map[string]string) {
+	m.heartbeats.observe(fields["path"], line, time.Now())
+	m.matchRules(line, fields, m.report)
+}
+
+// Result 规则测试中单条规则的命中结果
+type Result struct {
+	RuleID     string
+	Name       string
+	Level      string
+	Fields     map[string]string // 来源附带的字段和命名捕获组提取的字段
+	Suppressed bool              // 是否被白名单抑制
+	Reason     string            // 抑制该告警的白名单条目的说明
+}
+
+// Evaluate 返回一条内容命中的规则，用于测试规则
+// 只检查白名单，不计数、不写告警日志，也不经过去重、阈值和心跳规则
+func (m *Matcher) Evaluate(line string, fields map[string]string) []Result {
+	var results []Result
+	now := time.Now()
+	m.matchRules(line, fields, func(rule Rule, alert Alert) {
+		result := Result{
+			RuleID: rule.ID,
+			Name:   rule.Name,
+			Level:  alert.Level,
+			Fields: alert.Fields,
+		}
+		if entry := m.allowed(alert, now); entry != nil {
+			result.Suppressed = true
+			result.Reason = entry.Reason
+		}
+		results = append(results, result)
+	})
+	return results
+}
+
+// matchRules 按优先级顺序求值规则，按求值方式对产生告警的规则调用 fn
+// 设置了 stop 的规则命中后不再求值后面的规则
+func (m *Matcher) matchRules(line string, fields map[string]string, fn func(rule Rule, alert Alert)) {
+	m.mu.RLock()
+	rules, filter := m.rules, m.filter
+	m.mu.RUnlock()
+
+	var candidates []uint64
+	if filter != nil {
+		candidates = filter.candidates(line)
 	}
+	var best *Rule // highest-severity 方式下目前级别最高的命中规则
+	var bestAlert Alert
+	for i := range rules {
+		if candidates != nil && candidates[i/64]&(1<<(i%64)) == 0 {
+			continue
+		}
+		rule := &rules[i]
+		if best != nil && !rule.Stop && rule.rank <= best.rank {
+			// 级别不高于已命中的规则，命中与否都不影响结果
+			continue
+		}
+		if rule.Event != "" && fields[parser.FieldEvent] != rule.Event {
+			continue
+		}
+		target := line
+		if rule.Field != "" {
+			value, ok := fields[rule.Field]
+			if !ok {
+				continue
+			}
+			target = value
+		}
+		captured, ok := rule.match(target)
+		if !ok {
+			continue
+		}
 
-	if err := scanner.Err(); err != nil {
-		return offset, fmt.Errorf("扫描文件失败: %v", err)
+		alert := Alert{
+			Level:   rule.Level,
+			Content: line,
+			Fields:  fields,
+		}
+		if len(captured) > 0 {
+			alert.Fields = make(map[string]string, len(fields)+len(captured))
+			for key, value := range fields {
+				alert.Fields[key] = value
+			}
+			for key, value := range captured {
+				alert.Fields[key] = value
+			}
+		}
+		if rule.when != nil && !rule.when.eval(line, alert.Fields) {
+			continue
+		}
+
+		switch m.evaluation {
+		case EvaluationHighestSeverity:
+			if best == nil || rule.rank > best.rank {
+				best, bestAlert = rule, alert
+			}
+		case EvaluationFirstMatch:
+			fn(*rule, alert)
+			return
+		default:
+			fn(*rule, alert)
+		}
+		if rule.Stop {
+			break
+		}
 	}
+	if best != nil {
+		fn(*best, bestAlert)
+	}
+}
 
-	return newOffset, nil
+// match 匹配目标内容，返回命名捕获组提取的字段，未参与匹配的捕获组不记录
+func (r *Rule) match(target string) (map[string]string, bool) {
+	if !r.captures {
+		return nil, r.Pattern.MatchString(target)
+	}
+	loc := r.Pattern.FindStringSubmatchIndex(target)
+	if loc == nil {
+		return nil, false
+	}
+	captured := make(map[string]string)
+	for i, name := range r.Pattern.SubexpNames() {
+		if name == "" || loc[2*i] < 0 {
+			continue
+		}
+		captured[name] = target[loc[2*i]:loc[2*i+1]]
+	}
+	return captured, true
 }
 
-// GetMatchCount 获取总匹配次数
-func (m *Matcher) GetMatchCount() int64 {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return m.matchCount
+// report 记录告警：排除白名单和重复的告警后计数并写入告警日志，字段作为单独的日志字段
+func (m *Matcher) report(rule Rule, alert Alert) {
+	now := time.Now()
+	if entry := m.allowed(alert, now); entry != nil {
+		metrics.SuppressedAlerts.WithLabelValues(rule.ID, rule.Level).Inc()
+		logger.Logger.Debug("告警已被白名单抑制",
+			zap.String("level", alert.Level),
+			zap.String("rule_id", rule.ID),
+			zap.String("content", alert.Content),
+			zap.String("reason", entry.Reason))
+		return
+	}
+	for _, t := range m.thresholds.observe(alert, now) {
+		reportThreshold(t)
+	}
+	if rule.Dedup.Window > 0 {
+		key := dedupKey(rule.ID, rule.Dedup.Fields, alert)
+		if m.dedup.duplicate(key, time.Duration(rule.Dedup.Window)*time.Second, now) {
+			metrics.DedupedAlerts.WithLabelValues(rule.ID, rule.Level).Inc()
+			return
+		}
+	}
+
+	m.mu.Lock()
+	m.matchCount++
+	m.mu.Unlock()
+
+	metrics.RuleMatches.WithLabelValues(rule.ID, rule.Level).Inc()
+	for _, label := range rule.Labels {
+		if value, ok := alert.Fields[label]; ok {
+			metrics.RuleFieldMatches.WithLabelValues(rule.ID, rule.Level, label, value).Inc()
+		}
+	}
+
+	logFields := []zap.Field{
+		zap.String("level", alert.Level),
+		zap.String("rule_id", rule.ID),
+		zap.String("content", alert.Content),
+	}
+	if rule.Name != "" {
+		logFields = append(logFields, zap.String("rule_name", rule.Name))
+	}
+	if len(rule.Tags) > 0 {
+		logFields = append(logFields, zap.Strings("tags", rule.Tags))
+	}
+	for _, key := range SortedKeys(alert.Fields) {
+		logFields = append(logFields, zap.String(key, alert.Fields[key]))
+	}
+	logger.Logger.Info("匹配到告警", logFields...)
 }
 
-// matchLine 匹配单行内容
-func (m *Matcher) matchLine(line string) {
-	for _, rule := range m.rules {
-		if rule.Pattern.MatchString(line) {
-			m.mu.Lock()
-			m.matchCount++
-			m.mu.Unlock()
+// reportThreshold 记录阈值规则产生的告警
+func reportThreshold(t ThresholdAlert) {
+	metrics.ThresholdAlerts.WithLabelValues(t.Rule.Name, t.Rule.Level).Inc()
+	logFields := []zap.Field{
+		zap.String("level", t.Rule.Level),
+		zap.String("threshold", t.Rule.Name),
+		zap.Int("count", t.Count),
+		zap.Int("window", t.Rule.Window),
+	}
+	for _, key := range SortedKeys(t.Group) {
+		logFields = append(logFields, zap.String(key, t.Group[key]))
+	}
+	if t.Rule.Distinct != "" {
+		logFields = append(logFields, zap.Strings(t.Rule.Distinct, t.Values))
+	}
+	logger.Logger.Info("达到告警阈值", logFields...)
+}
 
-			metrics.RuleMatches.WithLabelValues(rule.Level).Inc()
-			logger.Logger.Info("匹配到告警",
-				zap.String("level", rule.Level),
-				zap.String("content", line))
+// allowed 返回抑制该告警的白名单条目，没有时返回 nil
+func (m *Matcher) allowed(alert Alert, now time.Time) *allowEntry {
+	for i := range m.allowlist {
+		if m.allowlist[i].allows(alert, now) {
+			return &m.allowlist[i]
 		}
 	}
+	return nil
+}
+
+// SortedKeys 返回排序后的字段名
+func SortedKeys(fields map[string]string) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
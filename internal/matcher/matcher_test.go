@@ -0,0 +1,55 @@
+package matcher
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuleID(t *testing.T) {
+	base := MatchRule{Pattern: "FOUND", Level: SeverityCritical}
+	if id := base.RuleID(); id != "rule-aab06f5f" {
+		t.Errorf("只设置 pattern 和 level 的规则 ID 为 %s，应保持为 rule-aab06f5f", id)
+	}
+
+	variants := []MatchRule{
+		base,
+		{Pattern: "FOUND", Level: SeverityCritical, When: &Condition{Contains: "Trojan"}},
+		{Pattern: "FOUND", Level: SeverityCritical, When: &Condition{Contains: "Worm"}},
+		{Pattern: "FOUND", Level: SeverityCritical, Labels: []string{"signature"}},
+		{Pattern: "FOUND", Level: SeverityCritical, Dedup: DedupConfig{Window: 60}},
+		{Pattern: "FOUND", Level: SeverityCritical, Priority: 10},
+		{Pattern: "FOUND", Level: SeverityCritical, Stop: true},
+	}
+	ids := make(map[string]int)
+	for i := range variants {
+		id := variants[i].RuleID()
+		if j, ok := ids[id]; ok {
+			t.Errorf("规则 %d 和规则 %d 生成了相同的 ID %s", j, i, id)
+		}
+		ids[id] = i
+	}
+
+	if id := (&MatchRule{ID: "clamd-found", Pattern: "FOUND"}).RuleID(); id != "clamd-found" {
+		t.Errorf("设置了 id 的规则 ID 为 %s", id)
+	}
+}
+
+func TestCompileRulesDuplicateIDs(t *testing.T) {
+	rule := MatchRule{Pattern: "FOUND", Level: SeverityCritical}
+	if _, err := compileRules([]MatchRule{rule, rule}); err == nil || !strings.Contains(err.Error(), "请为规则设置 id") {
+		t.Errorf("内容相同的规则返回 %v，应要求设置 id", err)
+	}
+
+	second := rule
+	second.ID = "found-2"
+	if _, err := compileRules([]MatchRule{rule, second}); err != nil {
+		t.Errorf("设置 id 后仍然失败: %v", err)
+	}
+
+	if err := checkRuleIDs([][]MatchRule{{rule}, {rule}}); err == nil {
+		t.Error("不同规则集中内容相同的规则应返回错误")
+	}
+	if err := checkRuleIDs([][]MatchRule{{second}, {second}}); err == nil || !strings.Contains(err.Error(), "规则 ID 重复: found-2") {
+		t.Errorf("重复的 id 返回 %v", err)
+	}
+}
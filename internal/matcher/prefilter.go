@@ -0,0 +1,264 @@
+package matcher
+
+import (
+	"regexp/syntax"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 提取必需字面量的限制
+const (
+	maxRuleLiterals = 32 // 单条规则最多使用的字面量个数，超过时不做预过滤
+)
+
+// prefilter 按规则的必需字面量预先筛选可能命中的规则
+// 每条规则的必需字面量是一组字符串，能匹配的内容至少包含其中一个（不区分 ASCII 大小写）
+// 所有字面量放在同一个 Aho-Corasick 自动机中，每行内容只扫描一次
+type prefilter struct {
+	ac       *ahoCorasick
+	literals [][]int  // 字面量对应的规则序号
+	always   []uint64 // 无法提取字面量、每行都要匹配的规则
+	words    int      // 位图的长度
+}
+
+// newPrefilter 为一组规则创建预过滤器，所有规则都无法提取字面量时返回 nil
+func newPrefilter(rules []Rule) *prefilter {
+	words := (len(rules) + 63) / 64
+	p := &prefilter{always: make([]uint64, words), words: words}
+	index := make(map[string]int)
+	var patterns []string
+	for i := range rules {
+		var lits []string
+		if rules[i].Field == "" {
+			lits = requiredLiterals(rules[i].Pattern.String())
+		}
+		if lits == nil {
+			p.always[i/64] |= 1 << (i % 64)
+			continue
+		}
+		for _, lit := range lits {
+			id, ok := index[lit]
+			if !ok {
+				id = len(patterns)
+				index[lit] = id
+				patterns = append(patterns, lit)
+				p.literals = append(p.literals, nil)
+			}
+			p.literals[id] = append(p.literals[id], i)
+		}
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+	p.ac = newAhoCorasick(patterns)
+	return p
+}
+
+// candidates 返回可能命中的规则的位图
+func (p *prefilter) candidates(line string) []uint64 {
+	set := make([]uint64, p.words)
+	copy(set, p.always)
+	p.ac.scan(line, func(id int) {
+		for _, i := range p.literals[id] {
+			set[i/64] |= 1 << (i % 64)
+		}
+	})
+	return set
+}
+
+// requiredLiterals 返回能匹配的内容必定包含其一的字面量，字面量已转为 ASCII 小写
+// 返回 nil 表示无法提取，规则需要对每行内容运行
+func requiredLiterals(pattern string) []string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	lits := literalsOf(re.Simplify())
+	if len(lits) == 0 || len(lits) > maxRuleLiterals {
+		return nil
+	}
+	return lits
+}
+
+// literalsOf 递归提取必需字面量，nil 表示没有限制
+func literalsOf(re *syntax.Regexp) []string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if len(re.Rune) == 0 {
+			return nil
+		}
+		if re.Flags&syntax.FoldCase != 0 && !asciiFoldable(re.Rune) {
+			return nil
+		}
+		return []string{asciiLower(string(re.Rune))}
+	case syntax.OpCapture, syntax.OpPlus:
+		return literalsOf(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min < 1 {
+			return nil
+		}
+		return literalsOf(re.Sub[0])
+	case syntax.OpConcat:
+		// 选择最短字面量最长的一组，越长的字面量误判越少
+		var best []string
+		for _, sub := range re.Sub {
+			lits := literalsOf(sub)
+			if lits != nil && (best == nil || shortest(lits) > shortest(best)) {
+				best = lits
+			}
+		}
+		return best
+	case syntax.OpAlternate:
+		var all []string
+		for _, sub := range re.Sub {
+			lits := literalsOf(sub)
+			if lits == nil {
+				return nil
+			}
+			all = append(all, lits...)
+			if len(all) > maxRuleLiterals {
+				return nil
+			}
+		}
+		return all
+	}
+	return nil
+}
+
+// asciiFoldable 判断忽略大小写的字面量能否按 ASCII 小写比较
+// 例如 k 忽略大小写时还匹配开尔文符号 U+212A，这种字面量不能用于预过滤
+func asciiFoldable(runes []rune) bool {
+	for _, r := range runes {
+		if r >= utf8.RuneSelf {
+			return false
+		}
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f >= utf8.RuneSelf {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// shortest 返回最短字面量的长度
+func shortest(lits []string) int {
+	n := len(lits[0])
+	for _, lit := range lits[1:] {
+		n = min(n, len(lit))
+	}
+	return n
+}
+
+// asciiLower 将 ASCII 大写字母转为小写，其他字节不变
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// ahoCorasick 多模式子串匹配，扫描时不区分 ASCII 大小写
+// 只为字面量中出现的字节分配转移表的列，其他字节都回到初始状态
+type ahoCorasick struct {
+	class    [256]uint8 // 字节到列的映射，0 表示未出现在字面量中的字节
+	width    int        // 转移表每个状态的列数
+	delta    []int32    // 完整的转移表
+	outputs  [][]int    // 在该状态结束的字面量
+	dictLink []int32    // 沿失败链接最近的有输出的状态，-1 表示没有
+}
+
+// newAhoCorasick 构建自动机，patterns 应为 ASCII 小写
+func newAhoCorasick(patterns []string) *ahoCorasick {
+	ac := &ahoCorasick{}
+	width := 1
+	for _, p := range patterns {
+		for i := 0; i < len(p); i++ {
+			c := p[i]
+			if ac.class[c] == 0 {
+				ac.class[c] = uint8(width)
+				width++
+			}
+		}
+	}
+	for c := 'A'; c <= 'Z'; c++ {
+		ac.class[c] = ac.class[c+'a'-'A']
+	}
+	ac.width = width
+
+	// 构建字典树，-1 表示没有边
+	newState := func() int32 {
+		for i := 0; i < width; i++ {
+			ac.delta = append(ac.delta, -1)
+		}
+		ac.outputs = append(ac.outputs, nil)
+		return int32(len(ac.outputs) - 1)
+	}
+	newState()
+	for id, p := range patterns {
+		s := int32(0)
+		for i := 0; i < len(p); i++ {
+			c := int32(ac.class[p[i]])
+			if ac.delta[s*int32(width)+c] < 0 {
+				next := newState()
+				ac.delta[s*int32(width)+c] = next
+			}
+			s = ac.delta[s*int32(width)+c]
+		}
+		ac.outputs[s] = append(ac.outputs[s], id)
+	}
+
+	// 按广度优先计算失败链接，同时补全转移表
+	fail := make([]int32, len(ac.outputs))
+	ac.dictLink = make([]int32, len(ac.outputs))
+	ac.dictLink[0] = -1
+	var queue []int32
+	for c := 0; c < width; c++ {
+		next := ac.delta[c]
+		if next < 0 {
+			ac.delta[c] = 0
+			continue
+		}
+		fail[next] = 0
+		ac.dictLink[next] = -1
+		queue = append(queue, next)
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for c := 0; c < width; c++ {
+			i := s*int32(width) + int32(c)
+			next := ac.delta[i]
+			if next < 0 {
+				ac.delta[i] = ac.delta[fail[s]*int32(width)+int32(c)]
+				continue
+			}
+			f := ac.delta[fail[s]*int32(width)+int32(c)]
+			fail[next] = f
+			if len(ac.outputs[f]) > 0 {
+				ac.dictLink[next] = f
+			} else {
+				ac.dictLink[next] = ac.dictLink[f]
+			}
+			queue = append(queue, next)
+		}
+	}
+	return ac
+}
+
+// scan 扫描内容，对出现的每个字面量调用 fn，同一字面量可能调用多次
+func (ac *ahoCorasick) scan(s string, fn func(id int)) {
+	width := int32(ac.width)
+	state := int32(0)
+	for i := 0; i < len(s); i++ {
+		state = ac.delta[state*width+int32(ac.class[s[i]])]
+		for o := state; o > 0; o = ac.dictLink[o] {
+			for _, id := range ac.outputs[o] {
+				fn(id)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package matcher
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// benchmarkRules 500 条病毒家族映射规则，加上几条常见的通用规则
+func benchmarkRules() []MatchRule {
+	rules := []MatchRule{
+		{Pattern: `(?i)error`, Level: SeverityError},
+		{Pattern: `SelfCheck: Database status OK`, Level: SeverityOK},
+		{Pattern: `(?P<file>/\S+): (?P<signature>\S+) FOUND`, Level: SeverityCritical},
+	}
+	for i := len(rules); i < 500; i++ {
+		rules = append(rules, MatchRule{
+			ID:      fmt.Sprintf("family-%03d", i),
+			Pattern: fmt.Sprintf(`(?P<signature>(Win|Unix)\.Trojan\.Family%03d-\d+)`, i),
+			Level:   SeverityAlert,
+		})
+	}
+	return rules
+}
+
+// benchmarkLines 繁忙的扫描日志，大部分是扫描通过的行
+var benchmarkLines = []string{
+	"Mon Oct 16 10:00:00 2026 -> /srv/www/uploads/2026/10/report-final.pdf: OK",
+	"Mon Oct 16 10:00:00 2026 -> /srv/www/uploads/2026/10/avatar.png: OK",
+	"Mon Oct 16 10:00:01 2026 -> /home/alice/.cache/pip/wheels/ab/cd/package.whl: OK",
+	"Mon Oct 16 10:00:01 2026 -> /var/mail/bob: OK",
+	"Mon Oct 16 10:00:02 2026 -> /srv/www/uploads/2026/10/invoice.doc: Win.Trojan.Family123-9876 FOUND",
+	"Mon Oct 16 10:00:02 2026 -> /tmp/build/node_modules/left-pad/index.js: OK",
+	"Mon Oct 16 10:00:03 2026 -> SelfCheck: Database status OK.",
+	"Mon Oct 16 10:00:03 2026 -> /srv/share/setup.exe: Unix.Trojan.Family450-1 FOUND",
+}
+
+func TestRequiredLiterals(t *testing.T) {
+	alternation := func(n int) string {
+		words := make([]string, n)
+		for i := range words {
+			words[i] = string("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"[i]) + "virus"
+		}
+		return strings.Join(words, "|")
+	}
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{`FOUND`, []string{"found"}},
+		{`(?P<signature>\S+) FOUND`, []string{" found"}},
+		{`^.*FOUND$`, []string{"found"}},
+		{`(?i)error`, []string{"error"}},
+		{`(?i)Error at line`, []string{"error at line"}},
+		{`病毒`, []string{"病毒"}},
+		// k 和 s 忽略大小写时还匹配开尔文符号和长 s，不能按 ASCII 比较
+		{`(?i)ok`, nil},
+		{`(?i)sum`, nil},
+		{`(?i)ok: (?P<file>\S+) FOUND`, []string{" found"}},
+		{`(?i)é`, nil},
+		{`Win\.Trojan|Unix\.Worm`, []string{"win.trojan", "unix.worm"}},
+		{`Win\.Trojan|.*`, nil},
+		{`(Win|Unix)\.Trojan\.Family001-\d+`, []string{".trojan.family001-"}},
+		{alternation(maxRuleLiterals + 1), nil},
+		{`(?:Trojan)?FOUND`, []string{"found"}},
+		{`Trojan?`, []string{"troja"}},
+		{`(?:Trojan)*`, nil},
+		{`(?:Trojan){0,}`, nil},
+		{`(?:Trojan){0,3}`, nil},
+		{`(?:Trojan){2,}`, []string{"trojan"}},
+		{`(?:Trojan)+`, []string{"trojan"}},
+		{`\d+`, nil},
+		{`(`, nil},
+	}
+	for _, tt := range tests {
+		if got := requiredLiterals(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("requiredLiterals(%q) = %q，应为 %q", tt.pattern, got, tt.want)
+		}
+	}
+	if got := requiredLiterals(alternation(maxRuleLiterals)); len(got) != maxRuleLiterals {
+		t.Errorf("%d 个分支的规则返回 %d 个字面量，应为 %d 个", maxRuleLiterals, len(got), maxRuleLiterals)
+	}
+}
+
+// TestPrefilterMatchesSequential 预过滤不应改变匹配结果
+func TestPrefilterMatchesSequential(t *testing.T) {
+	rules := append(benchmarkRules(),
+		MatchRule{Pattern: `(?i)database status ok`, Level: SeverityOK},
+		MatchRule{Pattern: `(?i)ok$`, Level: SeverityOK},
+		MatchRule{Pattern: `\d+ FOUND`, Level: SeverityCritical},
+		MatchRule{Pattern: `hostname`, Field: "hostname", Level: SeverityInfo},
+	)
+	compiled, err := compileRules(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sequential := &Matcher{rules: compiled}
+	filtered := &Matcher{rules: compiled, filter: newPrefilter(compiled)}
+
+	lines := append([]string{
+		"",
+		"ERROR: Can't connect to clamd",
+		"LibClamAV Error: cli_loaddb(): No supported database files found",
+		"SELFCHECK: DATABASE STATUS OK.",
+		"/tmp/x: Win.Trojan.Family001-1 found",
+		"/tmp/x: WIN.TROJAN.FAMILY001-1 FOUND",
+		"/tmp/x: Unix.Trojan.Family499-2 FOUND",
+		"/tmp/x: Unix.Trojan.Family500-2 FOUND",
+		"scan O\u212a",
+		"2 FOUND 3 FOUND",
+	}, benchmarkLines...)
+	for _, line := range lines {
+		fields := map[string]string{"hostname": "hostname-1"}
+		var want, got []string
+		sequential.matchRules(line, fields, func(r Rule, _ Alert) { want = append(want, r.ID) })
+		filtered.matchRules(line, fields, func(r Rule, _ Alert) { got = append(got, r.ID) })
+		if fmt.Sprint(want) != fmt.Sprint(got) {
+			t.Errorf("%q: 预过滤后命中 %v，应为 %v", line, got, want)
+		}
+	}
+}
+
+// BenchmarkMatch500Rules 每行内容的匹配耗时，prefilter 使用字面量预过滤，sequential 依次运行每条规则的正则
+func BenchmarkMatch500Rules(b *testing.B) {
+	compiled, err := compileRules(benchmarkRules())
+	if err != nil {
+		b.Fatal(err)
+	}
+	filter := newPrefilter(compiled)
+
+	for _, bc := range []struct {
+		name   string
+		filter *prefilter
+	}{
+		{"prefilter", filter},
+		{"sequential", nil},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			m := &Matcher{rules: compiled, filter: bc.filter}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.matchRules(benchmarkLines[i%len(benchmarkLines)], nil, func(Rule, Alert) {})
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"ClamGuardian/internal/logger"
+	"ClamGuardian/internal/metrics"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// reloadDelay 规则文件变化后等待的时间，编辑器保存时往往连续产生多个事件
+const reloadDelay = 500 * time.Millisecond
+
+// RuleFile 规则文件的内容
+type RuleFile struct {
+	Rules []MatchRule `mapstructure:"rules"`
+}
+
+// LoadRulesDir 按文件名顺序读取目录中的 .yaml 和 .yml 规则文件，不读取子目录和隐藏文件
+func LoadRulesDir(dir string) ([]MatchRule, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取规则目录失败: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || !isRuleFile(entry.Name()) {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	var rules []MatchRule
+	for _, name := range names {
+		file, err := LoadRuleFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, file.Rules...)
+	}
+	return rules, nil
+}
+
+// LoadRuleFile 读取一个规则文件并检查其中的规则
+func LoadRuleFile(path string) (*RuleFile, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("读取规则文件 %s 失败: %v", path, err)
+	}
+	var file RuleFile
+	if err := v.Unmarshal(&file); err != nil {
+		return nil, fmt.Errorf("解析规则文件 %s 失败: %v", path, err)
+	}
+	if _, err := compileRules(file.Rules); err != nil {
+		return nil, fmt.Errorf("规则文件 %s: %v", path, err)
+	}
+	return &file, nil
+}
+
+// isRuleFile 判断文件名是否为规则文件
+func isRuleFile(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	ext := filepath.Ext(name)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// AllRules 返回 config.yaml 中的规则和规则目录中的规则
+func (c *Config) AllRules() ([]MatchRule, error) {
+	if c.RulesDir == "" {
+		return c.Rules, nil
+	}
+	dirRules, err := LoadRulesDir(c.RulesDir)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]MatchRule{}, c.Rules...), dirRules...), nil
+}
+
+// checkRuleIDs 检查规则 ID（包括生成的 ID）在所有规则中没有重复
+func checkRuleIDs(sets [][]MatchRule) error {
+	ids := make(map[string]bool)
+	for _, rules := range sets {
+		for i := range rules {
+			id := rules[i].RuleID()
+			if ids[id] {
+				return duplicateIDError(&rules[i])
+			}
+			ids[id] = true
+		}
+	}
+	return nil
+}
+
+// ReloadRules 重新读取规则目录，所有规则检查通过后同时替换本匹配器和 WithRules 创建的匹配器的规则
+// 失败时保留原有规则并返回错误；去重、阈值和心跳状态不受影响
+func (m *Matcher) ReloadRules() error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	if err := m.reloadRules(); err != nil {
+		metrics.RuleReloadFailures.Inc()
+		return err
+	}
+	metrics.RuleReloads.Inc()
+	return nil
+}
+
+// reloadRules 编译新的规则并替换，调用方持有 reloadMu
+func (m *Matcher) reloadRules() error {
+	global, err := (&Config{Rules: m.base, RulesDir: m.rulesDir}).AllRules()
+	if err != nil {
+		return err
+	}
+
+	// 与启动时相同，监控路径的规则标签必须仍然选择到规则
+	sets := [][]MatchRule{global}
+	for _, d := range m.derived {
+		if err := CheckRuleTags(global, d.ruleTags); err != nil {
+			return err
+		}
+		sets = append(sets, d.pathRules)
+	}
+	if err := checkRuleIDs(sets); err != nil {
+		return err
+	}
+
+	rules, err := compileRules(global)
+	if err != nil {
+		return err
+	}
+	derived := make([][]Rule, len(m.derived))
+	for i, d := range m.derived {
+		if derived[i], err = compileRules(d.selectRules(global)); err != nil {
+			return err
+		}
+	}
+
+	m.setRules(rules)
+	for i, d := range m.derived {
+		d.setRules(derived[i])
+	}
+	m.global = global
+	logger.Logger.Info("规则已重新加载",
+		zap.String("rules_dir", m.rulesDir),
+		zap.Int("rules", len(global)))
+	return nil
+}
+
+// setRules 替换匹配使用的规则，同时重建字面量预过滤器
+func (m *Matcher) setRules(rules []Rule) {
+	filter := newPrefilter(rules)
+	m.mu.Lock()
+	m.rules, m.filter = rules, filter
+	m.mu.Unlock()
+}
+
+// watchRules 监视规则目录，规则文件变化后重新加载，直到 ctx 结束
+// 同时监视上级目录：规则目录被删除或移走后对它的监视随之失效，以原路径重新出现时再次加入
+func (m *Matcher) watchRules(ctx context.Context) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Logger.Error("创建规则目录监视器失败", zap.Error(err))
+		return
+	}
+	defer watcher.Close()
+	dir := filepath.Clean(m.rulesDir)
+	if err := watcher.Add(dir); err != nil {
+		logger.Logger.Error("监视规则目录失败",
+			zap.String("rules_dir", dir),
+			zap.Error(err))
+		return
+	}
+	if err := watcher.Add(filepath.Dir(dir)); err != nil {
+		logger.Logger.Warn("监视规则目录的上级目录失败，规则目录被移走后无法恢复监视",
+			zap.String("rules_dir", dir),
+			zap.Error(err))
+	}
+
+	watching := true
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			switch {
+			case event.Name == dir && event.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+				if !watching {
+					continue
+				}
+				// 被移走的目录仍会产生事件，不能再按原路径处理
+				watcher.Remove(dir)
+				watching = false
+				timer.Stop()
+				logger.Logger.Warn("规则目录被删除或移走，继续使用原有规则",
+					zap.String("rules_dir", dir))
+			case event.Name == dir && event.Op&fsnotify.Create != 0:
+				if err := watcher.Add(dir); err != nil {
+					logger.Logger.Error("监视规则目录失败",
+						zap.String("rules_dir", dir),
+						zap.Error(err))
+					continue
+				}
+				watching = true
+				logger.Logger.Info("规则目录重新出现，恢复监视",
+					zap.String("rules_dir", dir))
+				timer.Reset(reloadDelay)
+			case watching && filepath.Dir(event.Name) == dir && isRuleFile(filepath.Base(event.Name)) && event.Op != fsnotify.Chmod:
+				timer.Reset(reloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Logger.Error("规则目录监视错误", zap.Error(err))
+		case <-timer.C:
+			if err := m.ReloadRules(); err != nil {
+				logger.Logger.Error("重新加载规则失败，继续使用原有规则", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package matcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeRule 在目录中写入只有一条规则的规则文件
+func writeRule(t *testing.T, dir, id, pattern string) {
+	t.Helper()
+	content := "rules:\n  - id: " + id + "\n    pattern: \"" + pattern + "\"\n    level: critical\n"
+	if err := os.WriteFile(filepath.Join(dir, "rules.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitMatch 等待内容命中指定的规则
+func waitMatch(t *testing.T, m *Matcher, line, id string) bool {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, r := range m.Evaluate(line, nil) {
+			if r.RuleID == id {
+				return true
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return false
+}
+
+func TestWatchRulesDirRecreated(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "rules.d")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeRule(t, dir, "first", "FOUND")
+
+	m, err := NewMatcher(Config{RulesDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.watchRules(ctx)
+	time.Sleep(100 * time.Millisecond)
+
+	writeRule(t, dir, "second", "FOUND")
+	if !waitMatch(t, m, "a.exe: Eicar FOUND", "second") {
+		t.Fatal("规则文件修改后未重新加载")
+	}
+
+	// 目录被移走后重新创建，新目录中的规则文件仍应被监视
+	if err := os.Rename(dir, dir+".old"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeRule(t, dir, "third", "FOUND")
+	if !waitMatch(t, m, "a.exe: Eicar FOUND", "third") {
+		t.Fatal("规则目录重新创建后未重新加载")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	writeRule(t, dir, "fourth", "FOUND")
+	if !waitMatch(t, m, "a.exe: Eicar FOUND", "fourth") {
+		t.Fatal("规则目录重新创建后修改规则文件未重新加载")
+	}
+}
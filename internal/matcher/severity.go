@@ -0,0 +1,40 @@
+package matcher
+
+// 告警级别，按严重程度从低到高排列
+const (
+	SeverityOK        = "ok"        // 正常结果，例如扫描通过
+	SeverityInfo      = "info"      // 一般信息
+	SeverityNotice    = "notice"    // 需要留意但无需处理
+	SeverityWarning   = "warning"   // 警告
+	SeverityError     = "error"     // 错误
+	SeverityCritical  = "critical"  // 严重，例如发现病毒
+	SeverityAlert     = "alert"     // 需要立即处理
+	SeverityEmergency = "emergency" // 紧急
+)
+
+// severities 按严重程度从低到高排列的全部级别
+var severities = []string{
+	SeverityOK,
+	SeverityInfo,
+	SeverityNotice,
+	SeverityWarning,
+	SeverityError,
+	SeverityCritical,
+	SeverityAlert,
+	SeverityEmergency,
+}
+
+// ValidSeverity 检查告警级别是否有效
+func ValidSeverity(level string) bool {
+	return severityRank(level) >= 0
+}
+
+// severityRank 返回告警级别的严重程度，数值越大越严重，无效的级别返回 -1
+func severityRank(level string) int {
+	for i, s := range severities {
+		if s == level {
+			return i
+		}
+	}
+	return -1
+}
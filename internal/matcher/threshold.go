@@ -0,0 +1,206 @@
+package matcher
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"ClamGuardian/internal/parser"
+)
+
+// 阈值规则的内存上限
+const (
+	maxThresholdCount  = 10000 // 单条阈值规则的最大阈值
+	maxThresholdGroups = 10000 // 单条阈值规则同时跟踪的最大分组数，超过后淘汰最久没有命中的分组
+)
+
+// ThresholdRule 阈值规则：时间窗口内同一分组的命中次数达到阈值时产生一条告警
+// 计入的是单行规则的命中（已排除白名单），告警后该分组重新计数
+type ThresholdRule struct {
+	Name       string   `mapstructure:"name"`
+	Level      string   `mapstructure:"level"`       // 阈值告警自身的级别
+	MatchLevel string   `mapstructure:"match_level"` // 只计入该级别的单行规则的命中，未设置时计入全部命中
+	Event      string   `mapstructure:"event"`       // 只计入解析器识别出的该类型事件
+	GroupBy    []string `mapstructure:"group_by"`    // 分组字段，例如 path、signature，未设置时所有命中为一组
+	Distinct   string   `mapstructure:"distinct"`    // 设置时计数该字段的不同取值，例如 hostname
+	Count      int      `mapstructure:"count"`       // 阈值
+	Window     int      `mapstructure:"window"`      // 时间窗口(秒)
+}
+
+// ThresholdAlert 阈值规则产生的告警
+type ThresholdAlert struct {
+	Rule   *ThresholdRule
+	Count  int
+	Group  map[string]string // 分组字段的值
+	Values []string          // 设置了 distinct 时窗口内的不同取值
+}
+
+// hit 窗口内的一次命中
+type hit struct {
+	at    time.Time
+	value string // distinct 字段的值
+}
+
+// windowGroup 单个分组在窗口内的命中，最多保留 Count 条
+type windowGroup struct {
+	hits []hit
+	last time.Time
+}
+
+// threshold 阈值规则的运行状态
+type threshold struct {
+	rule   ThresholdRule
+	window time.Duration
+	mu     sync.Mutex
+	groups map[string]*windowGroup
+}
+
+// thresholds 所有阈值规则，由同一配置创建的匹配器共享
+type thresholds struct {
+	list []*threshold
+}
+
+// newThresholds 检查并创建阈值规则
+func newThresholds(rules []ThresholdRule) (*thresholds, error) {
+	t := &thresholds{}
+	names := make(map[string]bool)
+	for _, r := range rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("阈值规则未设置名称")
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("阈值规则名称重复: %s", r.Name)
+		}
+		names[r.Name] = true
+		if r.Count < 1 || r.Count > maxThresholdCount {
+			return nil, fmt.Errorf("阈值规则 %s 的 count 必须在 1 到 %d 之间", r.Name, maxThresholdCount)
+		}
+		if r.Window < 1 {
+			return nil, fmt.Errorf("阈值规则 %s 的时间窗口必须大于 0", r.Name)
+		}
+		if !ValidSeverity(r.Level) {
+			return nil, fmt.Errorf("阈值规则 %s 的告警级别无效: %q", r.Name, r.Level)
+		}
+		if r.MatchLevel != "" && !ValidSeverity(r.MatchLevel) {
+			return nil, fmt.Errorf("阈值规则 %s 的 match_level 无效: %q", r.Name, r.MatchLevel)
+		}
+		if r.Event != "" && !parser.ValidEvent(r.Event) {
+			return nil, fmt.Errorf("阈值规则 %s 的事件类型无效: %s", r.Name, r.Event)
+		}
+		t.list = append(t.list, &threshold{
+			rule:   r,
+			window: time.Duration(r.Window) * time.Second,
+			groups: make(map[string]*windowGroup),
+		})
+	}
+	return t, nil
+}
+
+// observe 记录一次单行规则的命中，返回达到阈值的告警
+func (t *thresholds) observe(alert Alert, now time.Time) []ThresholdAlert {
+	var fired []ThresholdAlert
+	for _, th := range t.list {
+		if a, ok := th.observe(alert, now); ok {
+			fired = append(fired, a)
+		}
+	}
+	return fired
+}
+
+// observe 记录一次命中，达到阈值时返回告警并清空该分组
+func (th *threshold) observe(alert Alert, now time.Time) (ThresholdAlert, bool) {
+	r := &th.rule
+	if r.MatchLevel != "" && alert.Level != r.MatchLevel {
+		return ThresholdAlert{}, false
+	}
+	if r.Event != "" && alert.Fields[parser.FieldEvent] != r.Event {
+		return ThresholdAlert{}, false
+	}
+	value := ""
+	if r.Distinct != "" {
+		var ok bool
+		if value, ok = alert.Fields[r.Distinct]; !ok {
+			return ThresholdAlert{}, false
+		}
+	}
+
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	key := groupKey(r.GroupBy, alert.Fields)
+	g := th.group(key, now)
+	g.last = now
+
+	// 去掉窗口之外的命中
+	cutoff := now.Add(-th.window)
+	i := 0
+	for i < len(g.hits) && !g.hits[i].at.After(cutoff) {
+		i++
+	}
+	g.hits = g.hits[i:]
+
+	if r.Distinct != "" {
+		// 同一取值只保留最近一次
+		for j, h := range g.hits {
+			if h.value == value {
+				g.hits = append(g.hits[:j], g.hits[j+1:]...)
+				break
+			}
+		}
+	}
+	g.hits = append(g.hits, hit{at: now, value: value})
+	if len(g.hits) < r.Count {
+		return ThresholdAlert{}, false
+	}
+
+	a := ThresholdAlert{
+		Rule:  r,
+		Count: len(g.hits),
+		Group: make(map[string]string, len(r.GroupBy)),
+	}
+	for _, field := range r.GroupBy {
+		a.Group[field] = alert.Fields[field]
+	}
+	if r.Distinct != "" {
+		for _, h := range g.hits {
+			a.Values = append(a.Values, h.value)
+		}
+	}
+	delete(th.groups, key)
+	return a, true
+}
+
+// group 返回分组的状态，分组数达到上限时先清理过期分组，仍然不足时淘汰最久没有命中的分组
+func (th *threshold) group(key string, now time.Time) *windowGroup {
+	if g, ok := th.groups[key]; ok {
+		return g
+	}
+	if len(th.groups) >= maxThresholdGroups {
+		cutoff := now.Add(-th.window)
+		var oldest string
+		for k, g := range th.groups {
+			if !g.last.After(cutoff) {
+				delete(th.groups, k)
+			} else if oldest == "" || g.last.Before(th.groups[oldest].last) {
+				oldest = k
+			}
+		}
+		if len(th.groups) >= maxThresholdGroups {
+			delete(th.groups, oldest)
+		}
+	}
+	g := &windowGroup{hits: make([]hit, 0, min(th.rule.Count, 16))}
+	th.groups[key] = g
+	return g
+}
+
+// groupKey 按分组字段的值计算分组键
+func groupKey(groupBy []string, fields map[string]string) string {
+	var b strings.Builder
+	for _, field := range groupBy {
+		b.WriteString(fields[field])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
@@ -3,20 +3,50 @@ package metrics
 import (
 	"encoding/json"
 	"net/http"
+	"sort"
+	"time"
 
 	"ClamGuardian/internal/position"
 )
 
-// FileStatusHandler 处理文件状态请求
-func FileStatusHandler(pm *position.Manager) http.HandlerFunc {
+// SkipReporter 提供被跳过的文件及原因
+type SkipReporter interface {
+	SkippedFiles() map[string]string
+}
+
+// HeartbeatStatus 心跳规则在单个文件上的状态
+type HeartbeatStatus struct {
+	Rule     string     `json:"rule"`
+	State    string     `json:"state"`    // pending、ok 或 missing
+	Interval int        `json:"interval"` // 最长间隔(秒)
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+}
+
+// HeartbeatReporter 提供各文件的心跳规则状态
+type HeartbeatReporter interface {
+	Heartbeats() map[string][]HeartbeatStatus
+}
+
+// fileStatus /files 返回的单个文件的状态
+type fileStatus struct {
+	Filename   string            `json:"filename"`
+	Position   int64             `json:"position"`
+	Size       int64             `json:"size"`
+	Progress   float64           `json:"progress"`
+	Skipped    bool              `json:"skipped,omitempty"`
+	Reason     string            `json:"reason,omitempty"`
+	Heartbeats []HeartbeatStatus `json:"heartbeats,omitempty"`
+}
+
+// FileStatusHandler 处理文件状态请求，被跳过的文件附带跳过原因，配置了心跳规则的文件附带心跳状态
+// heartbeats 可以为 nil
+func FileStatusHandler(pm *position.Manager, heartbeats HeartbeatReporter, reporters ...SkipReporter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		positions := pm.GetAllPositions()
 
-		type fileStatus struct {
-			Filename string  `json:"filename"`
-			Position int64   `json:"position"`
-			Size     int64   `json:"size"`
-			Progress float64 `json:"progress"`
+		var beats map[string][]HeartbeatStatus
+		if heartbeats != nil {
+			beats = heartbeats.Heartbeats()
 		}
 
 		status := make([]fileStatus, 0, len(positions))
@@ -26,11 +56,42 @@ func FileStatusHandler(pm *position.Manager) http.HandlerFunc {
 				progress = float64(pos.Position) / float64(pos.FileSize)
 			}
 			status = append(status, fileStatus{
-				Filename: pos.Filename,
-				Position: pos.Position,
-				Size:     pos.FileSize,
-				Progress: progress,
+				Filename:   pos.Filename,
+				Position:   pos.Position,
+				Size:       pos.FileSize,
+				Progress:   progress,
+				Heartbeats: beats[pos.Filename],
 			})
+			delete(beats, pos.Filename)
+		}
+
+		// 尚未读取过的文件也可能有心跳状态，例如预期存在但一直没有内容的文件
+		filenames := make([]string, 0, len(beats))
+		for filename := range beats {
+			filenames = append(filenames, filename)
+		}
+		sort.Strings(filenames)
+		for _, filename := range filenames {
+			status = append(status, fileStatus{
+				Filename:   filename,
+				Heartbeats: beats[filename],
+			})
+		}
+
+		for _, reporter := range reporters {
+			skipped := reporter.SkippedFiles()
+			filenames := make([]string, 0, len(skipped))
+			for filename := range skipped {
+				filenames = append(filenames, filename)
+			}
+			sort.Strings(filenames)
+			for _, filename := range filenames {
+				status = append(status, fileStatus{
+					Filename: filename,
+					Skipped:  true,
+					Reason:   skipped[filename],
+				})
+			}
 		}
 
 		w.Header().Set("Content-Type", "application/json")
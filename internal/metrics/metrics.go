@@ -30,6 +30,96 @@ var (
 			Name: "clamguardian_rule_matches_total",
 			Help: "规则匹配命中总数",
 		},
-		[]string{"level"},
+		[]string{"rule", "level"},
 	)
+
+	// RuleFieldMatches 按规则指定的字段值统计的命中数，字段由规则的 labels 选择
+	RuleFieldMatches = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "clamguardian_rule_field_matches_total",
+			Help: "按字段值统计的规则匹配命中总数",
+		},
+		[]string{"rule", "level", "field", "value"},
+	)
+
+	// ThresholdAlerts 阈值规则产生的告警数
+	ThresholdAlerts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "clamguardian_threshold_alerts_total",
+			Help: "阈值规则产生的告警总数",
+		},
+		[]string{"threshold", "level"},
+	)
+
+	// HeartbeatMissing 心跳规则在各文件上是否处于丢失状态
+	HeartbeatMissing = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "clamguardian_heartbeat_missing",
+			Help: "心跳规则是否超时未出现（1 为丢失）",
+		},
+		[]string{"heartbeat", "path"},
+	)
+
+	// SuppressedAlerts 被白名单抑制的告警数
+	SuppressedAlerts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "clamguardian_suppressed_alerts_total",
+			Help: "被白名单抑制的告警总数",
+		},
+		[]string{"rule", "level"},
+	)
+
+	// DedupedAlerts 时间窗口内重复而未记录的告警数
+	DedupedAlerts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "clamguardian_deduplicated_alerts_total",
+			Help: "去重时间窗口内重复而未记录的告警总数",
+		},
+		[]string{"rule", "level"},
+	)
+
+	// RuleReloads 规则目录重新加载成功的次数
+	RuleReloads = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "clamguardian_rule_reloads_total",
+		Help: "规则重新加载成功的次数",
+	})
+
+	// RuleReloadFailures 规则目录重新加载失败的次数，失败时继续使用原有规则
+	RuleReloadFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "clamguardian_rule_reload_failures_total",
+		Help: "规则重新加载失败的次数",
+	})
+
+	// ClamavEvents 内置解析器识别出的 ClamAV 事件数
+	ClamavEvents = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "clamguardian_clamav_events_total",
+			Help: "解析出的 ClamAV 事件总数",
+		},
+		[]string{"event"},
+	)
+
+	// SyslogMessages 接收到的 syslog 消息数
+	SyslogMessages = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "clamguardian_syslog_messages_total",
+			Help: "接收到的 syslog 消息总数",
+		},
+		[]string{"protocol", "format"},
+	)
+
+	// LongLines 超过最大长度的行数
+	LongLines = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "clamguardian_long_lines_total",
+			Help: "超过最大长度的行总数",
+		},
+		[]string{"policy"},
+	)
+
+	// CoalescedEvents 被合并的文件写入事件数
+	CoalescedEvents = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "clamguardian_coalesced_events_total",
+		Help: "与前一个事件合并处理的文件写入事件总数",
+	})
 )
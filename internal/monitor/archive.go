@@ -0,0 +1,153 @@
+package monitor
+
+import (
+	"io"
+	"os"
+
+	"ClamGuardian/internal/logger"
+	"ClamGuardian/internal/position"
+	"ClamGuardian/internal/reader"
+	"go.uber.org/zap"
+)
+
+// archiveState 获取压缩归档的处理记录，文件已被替换时视为没有记录
+func (m *Monitor) archiveState(filename string, id fileID) (position.FileState, bool) {
+	state, ok := m.store.GetState(filename)
+	if !ok || !state.Compressed {
+		return position.FileState{}, false
+	}
+	if id.valid() && (state.Inode != id.ino || state.Device != id.dev) {
+		return position.FileState{}, false
+	}
+	return state, true
+}
+
+// handleArchive 处理压缩归档
+// 归档不会再增长，完整解压读取一次后记录为已完成；轮转前已读取过的内容通过头部指纹跳过
+func (m *Monitor) handleArchive(filename string) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		logger.Logger.Error("获取文件信息失败", zap.Error(err))
+		return
+	}
+	id := fileIdentity(info)
+
+	state, ok := m.archiveState(filename, id)
+	if ok && state.Completed {
+		return
+	}
+	if !ok {
+		state = position.FileState{
+			Device:     id.dev,
+			Inode:      id.ino,
+			Compressed: true,
+		}
+		head, err := readArchiveHead(filename)
+		if err != nil {
+			// 头部尚不完整时无法判断内容是否已处理过，等待写入完成
+			logger.Logger.Debug("压缩文件头部尚不可用",
+				zap.String("filename", filename),
+				zap.Error(err))
+			return
+		}
+		if len(head) > 0 {
+			state.Fingerprint, state.FingerprintSize = fingerprint(head), int64(len(head))
+		}
+		if source, sourceState, found := m.findByHead(filename, head); found {
+			state.Offset = sourceState.Offset
+			logger.Logger.Info("压缩归档的部分内容已在轮转前处理",
+				zap.String("filename", filename),
+				zap.String("source", source),
+				zap.Int64("offset", state.Offset))
+		}
+	}
+
+	asm := reader.NewAssembler(m.multiline(filename), func(line string, _ int64) {
+		m.emit(filename, line, true)
+	})
+	newPos, err := reader.ReadFile(filename, state.Offset, m.linesFor(filename), asm)
+	state.Offset = newPos
+	state.Completed = err == nil
+	if state.Completed {
+		asm.Flush()
+	} else if start, ok := asm.Pending(); ok {
+		// 未结束的多行事件留待下次连同后续内容一起处理
+		state.Offset = start
+	}
+	m.store.UpdateState(filename, state)
+
+	if err != nil {
+		// 压缩文件可能仍在写入，等待下一次事件继续处理
+		logger.Logger.Warn("处理压缩文件未完成",
+			zap.String("filename", filename),
+			zap.Int64("offset", newPos),
+			zap.Error(err))
+		return
+	}
+	logger.Logger.Info("压缩归档处理完成",
+		zap.String("filename", filename),
+		zap.Int64("size", newPos))
+}
+
+// skipArchive 将压缩归档标记为已完成而不读取其内容
+func (m *Monitor) skipArchive(filename string) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		logger.Logger.Error("获取文件信息失败", zap.Error(err))
+		return
+	}
+	// 空文件可能正在写入，写入的内容不是启动前已有的内容
+	if info.Size() == 0 {
+		return
+	}
+	id := fileIdentity(info)
+	m.store.UpdateState(filename, position.FileState{
+		Device:     id.dev,
+		Inode:      id.ino,
+		Compressed: true,
+		Completed:  true,
+	})
+}
+
+// readArchiveHead 读取解压后的头部内容
+// 内容不足 fingerprintSize 字节时必须已读到归档末尾，否则返回错误
+func readArchiveHead(filename string) ([]byte, error) {
+	file, err := reader.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// 不使用 io.ReadFull：它会把 io.EOF 转换为 io.ErrUnexpectedEOF，无法区分截断的压缩流
+	head := make([]byte, fingerprintSize)
+	n := 0
+	for n < len(head) {
+		read, err := file.Read(head[n:])
+		n += read
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return head[:n], nil
+}
+
+// findByHead 按头部指纹查找内容相同的已处理文件，返回其中读取位置最大的记录
+func (m *Monitor) findByHead(filename string, head []byte) (string, position.FileState, bool) {
+	var found string
+	var foundState position.FileState
+	for name, state := range m.store.Snapshot() {
+		if name == filename || state.FingerprintSize == 0 || state.FingerprintSize > int64(len(head)) {
+			continue
+		}
+		if fingerprint(head[:state.FingerprintSize]) != state.Fingerprint {
+			continue
+		}
+		if found == "" || state.Offset > foundState.Offset {
+			found, foundState = name, state
+		}
+	}
+	return found, foundState, found != ""
+}
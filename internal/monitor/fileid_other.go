@@ -0,0 +1,10 @@
+//go:build !unix
+
+package monitor
+
+import "os"
+
+// fileIdentity 当前平台不支持 inode，只能依赖大小和指纹识别轮转
+func fileIdentity(info os.FileInfo) fileID {
+	return fileID{}
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package monitor
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIdentity 获取文件的设备号和 inode
+func fileIdentity(info os.FileInfo) fileID {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}
+}
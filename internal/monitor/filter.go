@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ClamGuardian/internal/glob"
+)
+
+// compileRegexps 编译排除正则
+func compileRegexps(patterns []string) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("编译排除正则失败 %s: %v", pattern, err)
+		}
+		regexps = append(regexps, re)
+	}
+	return regexps, nil
+}
+
+// matchFile 检查文件是否需要处理，并记录被跳过的文件及原因
+func (m *Monitor) matchFile(filename string) bool {
+	reason := m.skipReason(filename)
+
+	m.skipMu.Lock()
+	defer m.skipMu.Unlock()
+	if reason == "" {
+		delete(m.skipped, filename)
+		return true
+	}
+	m.skipped[filename] = reason
+	return false
+}
+
+// skipReason 返回文件被跳过的原因，需要处理时返回空字符串
+// 监控路径设置了 include 时代替全局的模式；全局和监控路径的排除规则都会检查
+func (m *Monitor) skipReason(filename string) string {
+	root := m.rootFor(filename)
+	if root == nil {
+		return "不在监控路径内"
+	}
+	patterns := m.patterns
+	if len(root.Include) > 0 {
+		patterns = root.Include
+	}
+	if !matchAny(patterns, root.Path, filename) {
+		return "不匹配文件模式"
+	}
+
+	for _, excludes := range [][]string{m.exclude, root.Exclude} {
+		for _, pattern := range excludes {
+			if glob.Match(pattern, root.Path, filename) {
+				return "匹配排除模式 " + pattern
+			}
+		}
+	}
+	for _, excludes := range [][]*regexp.Regexp{m.excludeRegex, root.excludeRegex} {
+		for _, re := range excludes {
+			if re.MatchString(filename) {
+				return "匹配排除正则 " + re.String()
+			}
+		}
+	}
+	return ""
+}
+
+// matchAny 判断路径是否匹配任一模式
+func matchAny(patterns []string, root, filename string) bool {
+	for _, pattern := range patterns {
+		if glob.Match(pattern, root, filename) {
+			return true
+		}
+	}
+	return false
+}
+
+// forgetSkipped 文件已不存在，不再记录跳过原因
+func (m *Monitor) forgetSkipped(filename string) {
+	m.skipMu.Lock()
+	defer m.skipMu.Unlock()
+	delete(m.skipped, filename)
+}
+
+// forgetSkippedUnder 目录已不存在，不再记录其中文件的跳过原因
+func (m *Monitor) forgetSkippedUnder(prefix string) {
+	m.skipMu.Lock()
+	defer m.skipMu.Unlock()
+	for filename := range m.skipped {
+		if strings.HasPrefix(filename, prefix) {
+			delete(m.skipped, filename)
+		}
+	}
+}
+
+// SkippedFiles 返回被跳过的文件及原因
+func (m *Monitor) SkippedFiles() map[string]string {
+	m.skipMu.Lock()
+	defer m.skipMu.Unlock()
+
+	skipped := make(map[string]string, len(m.skipped))
+	for filename, reason := range m.skipped {
+		skipped[filename] = reason
+	}
+	return skipped
+}
@@ -0,0 +1,30 @@
+//go:build linux
+
+package monitor
+
+import "syscall"
+
+// networkFSTypes 收不到 inotify 事件的文件系统类型（statfs 的 f_type）
+var networkFSTypes = map[uint32]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x794c7630: "overlay",
+	0x65735546: "fuse",
+	0x00c36400: "ceph",
+	0x01021997: "9p",
+	0x5346414f: "afs",
+	0x0bd00bd0: "lustre",
+}
+
+// isNetworkFS 判断路径是否位于网络文件系统或 overlay 上
+func isNetworkFS(path string) bool {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return false
+	}
+	// f_type 在 32 位平台上是有符号的 int32，转为 int64 会符号扩展，按 uint32 比较
+	_, ok := networkFSTypes[uint32(stat.Type)]
+	return ok
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package monitor
+
+// isNetworkFS 当前平台无法识别文件系统类型，默认使用 inotify
+func isNetworkFS(path string) bool {
+	return false
+}
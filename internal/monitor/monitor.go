@@ -5,76 +5,214 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"sync"
+	"time"
 
 	"ClamGuardian/internal/logger"
-	"ClamGuardian/internal/matcher"
 	"ClamGuardian/internal/metrics"
+	"ClamGuardian/internal/parser"
 	"ClamGuardian/internal/position"
+	"ClamGuardian/internal/reader"
+	"ClamGuardian/internal/source"
 	"github.com/fsnotify/fsnotify"
 	"go.uber.org/zap"
 )
 
-// Monitor 文件监控器
+// Store 读取位置的持久化存储，通常为 *position.Manager
+type Store interface {
+	GetState(filename string) (position.FileState, bool)
+	UpdateState(filename string, state position.FileState)
+	RetirePosition(filename string)
+	Snapshot() map[string]position.FileState
+}
+
+// Options 监控器配置
+type Options struct {
+	Name         string // 输入名称，记录在事件的来源信息中
+	Paths        []PathConfig
+	Patterns     []string
+	Exclude      []string           // 排除的文件模式，对所有监控路径生效
+	ExcludeRegex []string           // 排除的完整路径正则，对所有监控路径生效
+	Lines        reader.LineOptions // 按行读取的配置
+	Backend      string             // 监控后端：inotify、poll 或 auto
+	PollInterval time.Duration      // 轮询后端的检查间隔
+	Workers      int                // 并行处理文件的 worker 数
+
+	// PathHandler 为配置了专用规则的监控路径创建处理函数
+	// 为 nil 或返回 nil 时该路径使用 Start 传入的处理函数
+	PathHandler func(p PathConfig) (source.Handler, error)
+}
+
+// Monitor 文件监控器，作为文件类型的输入源
+//
+// 文件事件按文件排队，由多个 worker 并行处理，同一文件的事件按顺序处理。
+// 持有 mu 的写锁时可以访问全部跟踪状态；只持有读锁时只能读取各个 map，
+// 访问其中的跟踪器还需要持有该跟踪器的锁。
 type Monitor struct {
-	watcher    *fsnotify.Watcher
-	paths      []string
-	patterns   []string
-	matcher    *matcher.Matcher
-	posManager *position.Manager
-	bufferSize int
-	mu         sync.RWMutex
-	fileCount  int
+	name         string
+	watcher      watcher
+	paths        []PathConfig
+	patterns     []string
+	exclude      []string
+	excludeRegex []*regexp.Regexp
+	dirs         map[string]struct{} // 已加入监控的目录
+	handler      source.Handler
+	store        Store
+	lines        reader.LineOptions
+	tailers      map[string]*tailer      // 正在跟踪的文件
+	rotated      map[string]*tailer      // 已被重命名、等待读完的旧文件，按原路径索引
+	finished     map[fileID]finishedFile // 已读完但尚未以新名称出现的轮转文件
+	skipped      map[string]string       // 被跳过的文件及原因，由 skipMu 保护
+	skipMu       sync.Mutex
+	queue        *fileQueue
+	workers      int
+	wg           sync.WaitGroup
+	mu           sync.RWMutex
 }
 
 // NewMonitor 创建新的监控器
-func NewMonitor(paths []string, patterns []string, m *matcher.Matcher, pm *position.Manager, bufferSize int) (*Monitor, error) {
-	w, err := fsnotify.NewWatcher()
+func NewMonitor(opts Options, store Store) (*Monitor, error) {
+	excludeRegex, err := compileRegexps(opts.ExcludeRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := newWatcher(opts.Backend, opts.PollInterval)
 	if err != nil {
 		return nil, fmt.Errorf("创建监控器失败: %v", err)
 	}
 
+	paths := make([]PathConfig, len(opts.Paths))
+	for i, p := range opts.Paths {
+		p.Path = filepath.Clean(p.Path)
+		if p.multiline, err = reader.CompileMultiline(p.Multiline); err != nil {
+			w.Close()
+			return nil, fmt.Errorf("监控路径 %s 的多行配置无效: %v", p.Path, err)
+		}
+		if p.parser, err = parser.New(p.Parser); err != nil {
+			w.Close()
+			return nil, fmt.Errorf("监控路径 %s 的解析器无效: %v", p.Path, err)
+		}
+		if p.excludeRegex, err = compileRegexps(p.ExcludeRegex); err != nil {
+			w.Close()
+			return nil, fmt.Errorf("监控路径 %s 的排除正则无效: %v", p.Path, err)
+		}
+		if opts.PathHandler != nil && p.HasRules() {
+			if p.handler, err = opts.PathHandler(p); err != nil {
+				w.Close()
+				return nil, fmt.Errorf("监控路径 %s 的规则无效: %v", p.Path, err)
+			}
+		}
+		paths[i] = p
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
 	return &Monitor{
-		watcher:    w,
-		paths:      paths,
-		patterns:   patterns,
-		matcher:    m,
-		posManager: pm,
-		bufferSize: bufferSize,
+		name:         opts.Name,
+		watcher:      w,
+		paths:        paths,
+		patterns:     opts.Patterns,
+		exclude:      opts.Exclude,
+		excludeRegex: excludeRegex,
+		dirs:         make(map[string]struct{}),
+		tailers:      make(map[string]*tailer),
+		rotated:      make(map[string]*tailer),
+		finished:     make(map[fileID]finishedFile),
+		skipped:      make(map[string]string),
+		queue:        newFileQueue(),
+		workers:      workers,
+		store:        store,
+		lines:        opts.Lines,
 	}, nil
 }
 
-// Start 开始监控
-func (m *Monitor) Start(ctx context.Context) error {
-	// 添加所有目录到监控
-	for _, path := range m.paths {
-		if err := m.watcher.Add(path); err != nil {
-			return fmt.Errorf("添加监控路径失败 %s: %v", path, err)
-		}
+// Name 输入名称
+func (m *Monitor) Name() string { return m.name }
+
+// Kind 输入类型
+func (m *Monitor) Kind() string { return source.KindFile }
+
+// Start 开始监控，读取到的事件交给 h 处理
+func (m *Monitor) Start(ctx context.Context, h source.Handler) error {
+	m.handler = h
+
+	// 添加所有目录到监控，递归路径会同时加入其子目录
+	files, err := m.addRoots()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < m.workers; i++ {
+		m.wg.Add(1)
+		go m.work()
 	}
 
+	// 处理启动前已存在的文件
+	m.scan(files)
+
 	go m.watch(ctx)
 	return nil
 }
 
+// addRoots 将所有监控路径加入监控，返回其中已存在的文件
+func (m *Monitor) addRoots() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var files []string
+	for i := range m.paths {
+		root := &m.paths[i]
+		info, err := os.Stat(root.Path)
+		if err != nil {
+			return nil, fmt.Errorf("添加监控路径失败 %s: %v", root.Path, err)
+		}
+		if !info.IsDir() {
+			if err := m.watcher.Add(root.Path); err != nil {
+				return nil, fmt.Errorf("添加监控路径失败 %s: %v", root.Path, err)
+			}
+			files = append(files, root.Path)
+			continue
+		}
+		files = append(files, m.addTree(root, root.Path)...)
+		if _, ok := m.dirs[root.Path]; !ok {
+			return nil, fmt.Errorf("添加监控路径失败 %s", root.Path)
+		}
+	}
+	return files, nil
+}
+
 // watch 监控文件变化
 func (m *Monitor) watch(ctx context.Context) {
+	// 定期检查等待超时的多行事件和未写完的行
+	flushTicker := time.NewTicker(time.Second)
+	defer flushTicker.Stop()
+	defer m.queue.close()
+
 	for {
 		select {
-		case event, ok := <-m.watcher.Events:
+		case event, ok := <-m.watcher.Events():
 			if !ok {
 				logger.Logger.Info("监控事件通道已关闭")
 				return
 			}
 			m.handleEvent(event)
 
-		case err, ok := <-m.watcher.Errors:
+		case err, ok := <-m.watcher.Errors():
 			if !ok {
 				logger.Logger.Info("监控错误通道已关闭")
 				return
 			}
 			logger.Logger.Error("监控错误", zap.Error(err))
 
+		case <-flushTicker.C:
+			m.flushExpired()
+
 		case <-ctx.Done():
 			logger.Logger.Info("监控服务停止")
 			return
@@ -82,80 +220,249 @@ func (m *Monitor) watch(ctx context.Context) {
 	}
 }
 
-// handleEvent 处理文件事件
-func (m *Monitor) handleEvent(event fsnotify.Event) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// work 从队列中取出文件并按顺序处理其事件
+func (m *Monitor) work() {
+	defer m.wg.Done()
+
+	for {
+		name, ops, ok := m.queue.pop()
+		if !ok {
+			return
+		}
+		for _, op := range ops {
+			m.handleFileEvent(name, op)
+		}
+		m.queue.done(name)
+	}
+}
 
-	// 检查文件是否匹配模式
-	matched := false
-	for _, pattern := range m.patterns {
-		if match, _ := filepath.Match(pattern, filepath.Base(event.Name)); match {
-			matched = true
-			break
+// handleEvent 处理监控事件：目录事件直接处理，文件事件按文件排队交给 worker
+func (m *Monitor) handleEvent(event fsnotify.Event) {
+	// 目录事件：新建的子目录加入监控，删除的目录移出记录
+	if event.Op&fsnotify.Create == fsnotify.Create {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			m.handleDirCreate(event.Name)
+			return
 		}
 	}
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 && m.handleDirRemove(event.Name) {
+		return
+	}
 
-	if !matched {
+	if !m.matchFile(event.Name) {
+		// 被跳过的文件删除或重命名后不再记录
+		if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+			m.forgetSkipped(event.Name)
+		}
 		return
 	}
 
 	switch {
 	case event.Op&fsnotify.Write == fsnotify.Write:
-		m.handleFileWrite(event.Name)
+		m.queue.push(event.Name, fsnotify.Write)
 	case event.Op&fsnotify.Create == fsnotify.Create:
-		m.handleFileCreate(event.Name)
+		m.queue.push(event.Name, fsnotify.Create)
 	case event.Op&fsnotify.Remove == fsnotify.Remove:
-		m.handleFileRemove(event.Name)
+		m.queue.push(event.Name, fsnotify.Remove)
+	case event.Op&fsnotify.Rename == fsnotify.Rename:
+		m.queue.push(event.Name, fsnotify.Rename)
+	}
+}
+
+// handleFileEvent 处理单个文件事件，由 worker 调用
+func (m *Monitor) handleFileEvent(filename string, op fsnotify.Op) {
+	switch op {
+	case fsnotify.Write:
+		m.handleFileWrite(filename)
+	case fsnotify.Create:
+		m.handleFileCreate(filename)
+	case fsnotify.Remove:
+		m.handleFileRemove(filename)
+	case fsnotify.Rename:
+		m.handleFileRename(filename)
+	}
+}
+
+// handleDirCreate 处理目录创建事件
+func (m *Monitor) handleDirCreate(dir string) {
+	root := m.rootFor(dir)
+	if root == nil || !root.allowDepth(depthOf(root.Path, dir)) {
+		return
+	}
+
+	logger.Logger.Info("检测到新目录",
+		zap.String("path", dir))
+
+	m.mu.Lock()
+	files := m.addTree(root, dir)
+	m.mu.Unlock()
+
+	// 目录加入监控前可能已有文件写入，这里补充处理一次
+	for _, filename := range files {
+		if m.matchFile(filename) {
+			m.queue.push(filename, fsnotify.Write)
+		}
+	}
+}
+
+// handleDirRemove 处理目录删除事件，不是已监控的目录时返回 false
+func (m *Monitor) handleDirRemove(dir string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.dirs[dir]; !ok {
+		return false
+	}
+
+	logger.Logger.Info("监控目录被删除",
+		zap.String("path", dir))
+
+	prefix := dir + string(filepath.Separator)
+	for path := range m.dirs {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			m.watcher.Remove(path)
+			delete(m.dirs, path)
+		}
 	}
+	m.forgetSkippedUnder(prefix)
+	return true
 }
 
 // handleFileWrite 处理文件写入事件
+// 轮转识别等需要修改跟踪状态的步骤持有写锁，读取内容时只持有跟踪器的锁，不阻塞其他文件
 func (m *Monitor) handleFileWrite(filename string) {
-	currentPos := m.posManager.GetPosition(filename)
+	if reader.IsCompressed(filename) {
+		m.handleArchive(filename)
+		return
+	}
+
 	fileInfo, err := os.Stat(filename)
 	if err != nil {
 		logger.Logger.Error("获取文件信息失败", zap.Error(err))
 		return
 	}
 
-	newPos, err := m.matcher.ProcessFile(filename, currentPos)
+	t, ok := m.current(filename, fileInfo)
+	if !ok {
+		if t, err = m.prepare(filename, fileInfo); err != nil {
+			logger.Logger.Error("处理文件失败", zap.Error(err))
+			return
+		}
+	}
+
+	more, err := m.readTracked(filename, t, fileInfo)
 	if err != nil {
 		logger.Logger.Error("处理文件失败", zap.Error(err))
 		return
 	}
+	// 单次读取有上限，剩余内容重新排队，期间其他文件可以得到处理
+	if more {
+		m.queue.push(filename, fsnotify.Write)
+	}
+}
+
+// current 返回可以直接读取的跟踪器，需要先处理轮转或开始跟踪时返回 false
+func (m *Monitor) current(filename string, info os.FileInfo) (*tailer, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.rotated[filename]; ok {
+		return nil, false
+	}
+	t, ok := m.tailers[filename]
+	if !ok || t.probe || (t.id.valid() && t.id != fileIdentity(info)) {
+		return nil, false
+	}
+	return t, true
+}
+
+// prepare 处理轮转和替换，返回该路径当前文件的跟踪器
+func (m *Monitor) prepare(filename string, info os.FileInfo) (*tailer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 原路径出现了新的写入，说明写入方已切换到新文件
+	m.finishRotated(filename)
+
+	t, ok := m.tailers[filename]
+	if ok && t.id.valid() && t.id != fileIdentity(info) {
+		// 路径已指向另一个文件（错过了重命名事件），先读完旧文件
+		logger.Logger.Info("检测到文件被替换",
+			zap.String("filename", filename))
+		m.finishRenamed(t)
+		delete(m.tailers, filename)
+		ok = false
+	}
+	if !ok {
+		var err error
+		if t, err = m.track(filename); err != nil {
+			return nil, err
+		}
+	}
+	if t.probe {
+		m.probeCopy(t, info.Size())
+	}
+	return t, nil
+}
+
+// readTracked 读取跟踪中的文件并更新状态，more 表示还有未读取的内容
+func (m *Monitor) readTracked(filename string, t *tailer, fileInfo os.FileInfo) (bool, error) {
+	// 准备读取期间文件可能已被轮转或删除
+	m.mu.RLock()
+	current := m.tailers[filename] == t
+	m.mu.RUnlock()
+	if !current {
+		return false, nil
+	}
+
+	// 读取时只持有跟踪器的锁，不阻塞其他文件的轮转识别和开始跟踪
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	more, err := m.readLimit(t)
+	if err != nil {
+		return false, err
+	}
 
 	// 更新状态管理器
+	progress := float64(0)
+	if fileInfo.Size() > 0 {
+		progress = float64(t.offset) / float64(fileInfo.Size())
+	}
 	stateManager := metrics.GetStateManager()
 	stateManager.UpdateFileStatus(filename, &metrics.FileStatus{
 		Filename:     filename,
-		Position:     newPos,
+		Position:     t.offset,
 		Size:         fileInfo.Size(),
-		Progress:     float64(newPos) / float64(fileInfo.Size()),
+		Progress:     progress,
 		LastModified: fileInfo.ModTime(),
 	})
 
-	m.posManager.UpdatePosition(filename, newPos)
+	m.saveState(t)
+	return more, nil
 }
 
 // handleFileCreate 处理文件创建事件
 func (m *Monitor) handleFileCreate(filename string) {
+	fileInfo, err := os.Stat(filename)
+	if err != nil {
+		logger.Logger.Error("获取文件信息失败", zap.Error(err))
+		return
+	}
+
+	// 被重命名的文件以新名称出现时沿用原有进度
 	m.mu.Lock()
-	m.fileCount++
+	adopted := m.adoptRotated(filename, fileIdentity(fileInfo))
 	m.mu.Unlock()
+	if adopted {
+		m.handleFileWrite(filename)
+		return
+	}
 
 	logger.Logger.Info("检测到新文件",
 		zap.String("filename", filename))
 
-	newPos, err := m.matcher.ProcessFile(filename, 0)
-	if err != nil {
-		logger.Logger.Error("处理新文件失败",
-			zap.String("filename", filename),
-			zap.Error(err))
-		return
-	}
-
-	m.posManager.UpdatePosition(filename, newPos)
+	m.handleFileWrite(filename)
 	if err := m.watcher.Add(filename); err != nil {
 		logger.Logger.Error("添加文件到监控失败",
 			zap.String("filename", filename),
@@ -164,20 +471,61 @@ func (m *Monitor) handleFileCreate(filename string) {
 	metrics.ProcessedFiles.Inc()
 }
 
+// handleFileRename 处理文件重命名事件
+func (m *Monitor) handleFileRename(filename string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.tailers[filename]
+	if !ok {
+		return
+	}
+	// 事件处理前路径可能已指向新文件并开始跟踪，此时的跟踪器不是被重命名的文件
+	if info, err := os.Stat(filename); err == nil && t.id.valid() && fileIdentity(info) == t.id {
+		return
+	}
+
+	logger.Logger.Info("文件被重命名",
+		zap.String("filename", filename))
+	m.retire(t)
+	m.store.UpdateState(filename, t.state())
+}
+
 // handleFileRemove 处理文件删除事件
 func (m *Monitor) handleFileRemove(filename string) {
 	m.mu.Lock()
-	m.fileCount--
-	m.mu.Unlock()
+	defer m.mu.Unlock()
 
 	logger.Logger.Info("文件被删除",
 		zap.String("filename", filename))
+
+	// 已打开的句柄仍可读取，先处理完删除前写入的内容
+	if t, ok := m.tailers[filename]; ok {
+		m.finish(t)
+		m.saveState(t)
+		delete(m.tailers, filename)
+		m.closePath(filename)
+	}
+
+	// 保留删除前的读取记录，轮转后被压缩的文件可以据此跳过已处理的内容
 	m.watcher.Remove(filename)
-	m.posManager.RemovePosition(filename)
+	m.store.RetirePosition(filename)
 }
 
-// Stop 停止监控
+// Stop 停止监控，等待正在处理的文件完成
 func (m *Monitor) Stop() error {
+	m.queue.close()
+	m.wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.tailers {
+		t.close()
+	}
+	for _, t := range m.rotated {
+		t.close()
+	}
 	return m.watcher.Close()
 }
 
@@ -185,5 +533,5 @@ func (m *Monitor) Stop() error {
 func (m *Monitor) GetFileCount() int {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.fileCount
+	return len(m.tailers)
 }
@@ -0,0 +1,287 @@
+package monitor
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"ClamGuardian/internal/position"
+	"ClamGuardian/internal/reader"
+	"ClamGuardian/internal/source"
+)
+
+// memStore 内存中的位置存储
+type memStore struct {
+	mu     sync.Mutex
+	states map[string]position.FileState
+}
+
+func newMemStore() *memStore {
+	return &memStore{states: make(map[string]position.FileState)}
+}
+
+func (s *memStore) GetState(filename string) (position.FileState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[filename]
+	return state, ok && state.RemovedAt == 0
+}
+
+func (s *memStore) UpdateState(filename string, state position.FileState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[filename] = state
+}
+
+func (s *memStore) RetirePosition(filename string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state, ok := s.states[filename]; ok {
+		state.RemovedAt = time.Now().Unix()
+		s.states[filename] = state
+	}
+}
+
+func (s *memStore) Snapshot() map[string]position.FileState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := make(map[string]position.FileState, len(s.states))
+	for name, state := range s.states {
+		snapshot[name] = state
+	}
+	return snapshot
+}
+
+// collector 记录处理函数收到的每一行
+type collector struct {
+	mu       sync.Mutex
+	lines    []string
+	backfill []string // 作为补读内容交出的行
+	closed   []string // 停止跟踪的路径
+}
+
+func (c *collector) Handle(ev source.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lines = append(c.lines, ev.Line)
+	if ev.Origin.Backfill {
+		c.backfill = append(c.backfill, ev.Line)
+	}
+}
+
+func (c *collector) ClosePath(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = append(c.closed, path)
+}
+
+func (c *collector) snapshot() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.lines...)
+}
+
+// waitLines 等待收到 n 行后再等待一段时间，确认没有多余的行
+func (c *collector) waitLines(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(c.snapshot()) < n && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(300 * time.Millisecond)
+	return c.snapshot()
+}
+
+// startMonitor 在临时目录上启动监控器
+func startMonitor(t *testing.T, dir, backend string, workers int, h source.Handler) *Monitor {
+	t.Helper()
+	return startMonitorWith(t, Options{
+		Name:         "files",
+		Paths:        []PathConfig{{Path: dir}},
+		Patterns:     []string{"clamd.*"},
+		Backend:      backend,
+		PollInterval: 20 * time.Millisecond,
+		Workers:      workers,
+	}, h)
+}
+
+// startMonitorWith 按给定的配置启动监控器
+func startMonitorWith(t *testing.T, opts Options, h source.Handler) *Monitor {
+	t.Helper()
+	m, err := NewMonitor(opts, newMemStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := m.Start(ctx, h); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		m.Stop()
+	})
+	return m
+}
+
+// appendLines 向文件追加编号从 from 到 to（不含）的行
+func appendLines(t *testing.T, filename string, from, to int) {
+	t.Helper()
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for i := from; i < to; i++ {
+		fmt.Fprintf(f, "line %03d\n", i)
+	}
+}
+
+// expectOnce 检查每一行恰好收到一次
+func expectOnce(t *testing.T, got []string, n int) {
+	t.Helper()
+	sorted := append([]string(nil), got...)
+	sort.Strings(sorted)
+	want := make([]string, n)
+	for i := range want {
+		want[i] = fmt.Sprintf("line %03d", i)
+	}
+	if fmt.Sprint(sorted) != fmt.Sprint(want) {
+		t.Errorf("收到 %d 行 %v，应恰好收到一次 line 000 到 line %03d", len(got), got, n-1)
+	}
+}
+
+func TestRenameRotationExactlyOnce(t *testing.T) {
+	for _, tc := range []struct {
+		backend string
+		workers int
+	}{
+		{BackendInotify, 1},
+		{BackendInotify, 4},
+		{BackendPoll, 1},
+		{BackendPoll, 4},
+	} {
+		t.Run(fmt.Sprintf("%s/%d", tc.backend, tc.workers), func(t *testing.T) {
+			for run := 0; run < 3; run++ {
+				dir := t.TempDir()
+				logFile := filepath.Join(dir, "clamd.log")
+				appendLines(t, logFile, 0, 10)
+
+				c := &collector{}
+				startMonitor(t, dir, tc.backend, tc.workers, c)
+				c.waitLines(t, 10)
+
+				// logrotate 的 create 方式：重命名后写入方在旧文件中追加最后一行，再创建新文件
+				appendLines(t, logFile, 10, 20)
+				if err := os.Rename(logFile, logFile+".1"); err != nil {
+					t.Fatal(err)
+				}
+				appendLines(t, logFile+".1", 20, 21)
+				appendLines(t, logFile, 21, 25)
+
+				expectOnce(t, c.waitLines(t, 25), 25)
+			}
+		})
+	}
+}
+
+func TestEmptyArchiveNotCompleted(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "clamd.log.1.gz")
+
+	c := &collector{}
+	startMonitor(t, dir, BackendInotify, 1, c)
+
+	// 压缩工具先创建空文件，再写入压缩后的内容
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	zw := gzip.NewWriter(f)
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(zw, "line %03d\n", i)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectOnce(t, c.waitLines(t, 5), 5)
+}
+
+func TestRenameClosesPath(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "clamd.log")
+	appendLines(t, logFile, 0, 5)
+
+	c := &collector{}
+	startMonitor(t, dir, BackendInotify, 1, c)
+	c.waitLines(t, 5)
+
+	if err := os.Rename(logFile, logFile+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendLines(t, logFile+".1", 5, 6)
+	appendLines(t, logFile, 6, 8)
+	expectOnce(t, c.waitLines(t, 8), 8)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if fmt.Sprint(c.closed) != fmt.Sprint([]string{logFile}) {
+		t.Errorf("停止跟踪的路径为 %v，应为 [%s]", c.closed, logFile)
+	}
+	// 处理重命名事件时已写入的内容按正在写入的文件处理，之后读到的才是补读的内容
+	for _, line := range c.backfill {
+		if line != "line 005" {
+			t.Errorf("补读的行为 %v，只有轮转后追加的 line 005 可以作为补读内容", c.backfill)
+			break
+		}
+	}
+}
+
+func TestPartialLineTimeout(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "clamd.log")
+
+	c := &collector{}
+	startMonitorWith(t, Options{
+		Name:     "files",
+		Paths:    []PathConfig{{Path: dir}},
+		Patterns: []string{"clamd.*"},
+		Lines:    reader.LineOptions{MaxLineSize: 4096, PartialTimeout: 1500 * time.Millisecond},
+	}, c)
+
+	f, err := os.Create(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fmt.Fprint(f, "line 000\nline 0")
+
+	// 写完的行立即处理，末尾未写完的行等待写完
+	if got := c.waitLines(t, 1); fmt.Sprint(got) != "[line 000]" {
+		t.Fatalf("收到 %v，应只收到 line 000", got)
+	}
+	fmt.Fprint(f, "01\n")
+	if got := c.waitLines(t, 2); fmt.Sprint(got) != "[line 000 line 001]" {
+		t.Fatalf("收到 %v，未写完的行写完后应作为一行处理", got)
+	}
+
+	// 写入方一直没有写完时，超时后按完整的行处理
+	fmt.Fprint(f, "line 002")
+	deadline := time.Now().Add(5 * time.Second)
+	for len(c.snapshot()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if got := c.snapshot(); fmt.Sprint(got) != "[line 000 line 001 line 002]" {
+		t.Errorf("收到 %v，未写完的行应在超时后处理", got)
+	}
+}
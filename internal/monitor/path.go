@@ -0,0 +1,127 @@
+package monitor
+
+import (
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"ClamGuardian/internal/logger"
+	"ClamGuardian/internal/matcher"
+	"ClamGuardian/internal/parser"
+	"ClamGuardian/internal/reader"
+	"ClamGuardian/internal/source"
+	"go.uber.org/zap"
+)
+
+// PathConfig 单个监控路径的配置
+type PathConfig struct {
+	Path      string `mapstructure:"path"`
+	Recursive bool   `mapstructure:"recursive"` // 是否递归监控子目录
+	MaxDepth  int    `mapstructure:"max_depth"` // 递归深度限制，0 表示不限制
+	StartAt   string `mapstructure:"start_at"`  // 没有保存位置的文件在启动时从何处开始读取
+	Encoding  string `mapstructure:"encoding"`  // 文件的字符编码，默认 UTF-8，文件开头有 BOM 时自动识别
+	Parser    string `mapstructure:"parser"`    // 内置解析器，例如 clamd，解析出的字段随事件一起交给规则
+
+	Include      []string            `mapstructure:"include"`       // 文件匹配模式，设置后代替全局的 patterns
+	Exclude      []string            `mapstructure:"exclude"`       // 排除的文件模式，写法与 patterns 相同
+	ExcludeRegex []string            `mapstructure:"exclude_regex"` // 排除的完整路径正则
+	Rules        []matcher.MatchRule `mapstructure:"rules"`         // 该路径专用的规则
+	RuleTags     []string            `mapstructure:"rule_tags"`     // 按标签选择全局规则，可与 rules 同时使用
+
+	Multiline reader.MultilineConfig `mapstructure:"multiline"` // 多行事件合并
+
+	multiline    *reader.Multiline
+	parser       parser.Parser
+	excludeRegex []*regexp.Regexp
+	handler      source.Handler // 使用专用规则时的处理函数，nil 表示使用输入的处理函数
+}
+
+// HasRules 是否配置了专用规则或规则标签
+func (p *PathConfig) HasRules() bool {
+	return len(p.Rules) > 0 || len(p.RuleTags) > 0
+}
+
+// depthOf 计算目录相对于监控根目录的深度
+func depthOf(root, dir string) int {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return len(strings.Split(filepath.ToSlash(rel), "/"))
+}
+
+// allowDepth 判断该深度的目录是否允许监控
+func (p *PathConfig) allowDepth(depth int) bool {
+	if depth == 0 {
+		return true
+	}
+	if !p.Recursive {
+		return false
+	}
+	return p.MaxDepth <= 0 || depth <= p.MaxDepth
+}
+
+// Contains 判断路径是否位于该监控根目录下
+func (p *PathConfig) Contains(name string) bool {
+	rel, err := filepath.Rel(p.Path, name)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// rootFor 查找路径所属的监控根目录，多个匹配时取最长的根目录
+func (m *Monitor) rootFor(name string) *PathConfig {
+	var found *PathConfig
+	for i := range m.paths {
+		root := &m.paths[i]
+		if root.Contains(name) && (found == nil || len(root.Path) > len(found.Path)) {
+			found = root
+		}
+	}
+	return found
+}
+
+// addTree 将目录及其允许深度内的子目录加入监控，并返回其中已存在的文件
+func (m *Monitor) addTree(root *PathConfig, dir string) []string {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			logger.Logger.Warn("遍历目录失败",
+				zap.String("path", path),
+				zap.Error(err))
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if !d.IsDir() {
+			files = append(files, path)
+			return nil
+		}
+
+		if !root.allowDepth(depthOf(root.Path, path)) {
+			return fs.SkipDir
+		}
+		if _, ok := m.dirs[path]; ok {
+			return nil
+		}
+		if err := m.watcher.Add(path); err != nil {
+			logger.Logger.Error("添加监控目录失败",
+				zap.String("path", path),
+				zap.Error(err))
+			return fs.SkipDir
+		}
+		m.dirs[path] = struct{}{}
+		logger.Logger.Debug("添加监控目录", zap.String("path", path))
+		return nil
+	})
+	if err != nil {
+		logger.Logger.Error("遍历监控目录失败",
+			zap.String("path", dir),
+			zap.Error(err))
+	}
+	return files
+}
@@ -0,0 +1,249 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileSnapshot 轮询时记录的文件状态
+type fileSnapshot struct {
+	id      fileID
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+// changed 判断文件内容是否可能发生变化
+func (s fileSnapshot) changed(other fileSnapshot) bool {
+	return s.size != other.size || !s.modTime.Equal(other.modTime)
+}
+
+// pollWatcher 基于定期 stat 的监控后端，用于收不到 inotify 事件的文件系统
+// 与 inotify 一致：监控目录时报告目录下条目的变化，监控文件时报告文件本身的变化
+type pollWatcher struct {
+	interval time.Duration
+	watches  map[string]map[string]fileSnapshot // 监控路径 -> 条目快照
+	events   chan fsnotify.Event
+	errors   chan error
+	done     chan struct{}
+	once     sync.Once
+	mu       sync.Mutex
+}
+
+// newPollWatcher 创建轮询监控后端
+func newPollWatcher(interval time.Duration) *pollWatcher {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	w := &pollWatcher{
+		interval: interval,
+		watches:  make(map[string]map[string]fileSnapshot),
+		events:   make(chan fsnotify.Event),
+		errors:   make(chan error),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Add 添加监控路径并记录初始状态
+func (w *pollWatcher) Add(path string) error {
+	snapshot, err := takeSnapshot(path)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.watches[path] = snapshot
+	return nil
+}
+
+// Remove 移除监控路径
+func (w *pollWatcher) Remove(path string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.watches[path]; !ok {
+		return fmt.Errorf("路径未被监控: %s", path)
+	}
+	delete(w.watches, path)
+	return nil
+}
+
+// Events 返回事件通道
+func (w *pollWatcher) Events() <-chan fsnotify.Event {
+	return w.events
+}
+
+// Errors 返回错误通道
+func (w *pollWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Close 停止轮询
+func (w *pollWatcher) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+	})
+	return nil
+}
+
+// run 定期检查所有监控路径
+func (w *pollWatcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	defer close(w.events)
+	defer close(w.errors)
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			// 在锁外发送事件，避免与正在调用 Add/Remove 的事件处理方互相等待
+			events, errs := w.poll()
+			for _, err := range errs {
+				select {
+				case w.errors <- err:
+				case <-w.done:
+					return
+				}
+			}
+			for _, event := range events {
+				select {
+				case w.events <- event:
+				case <-w.done:
+					return
+				}
+			}
+		}
+	}
+}
+
+// poll 比较所有监控路径的当前状态与上次快照，生成事件
+func (w *pollWatcher) poll() ([]fsnotify.Event, []error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var events []fsnotify.Event
+	var errs []error
+	for path, previous := range w.watches {
+		current, err := takeSnapshot(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// 监控路径本身被删除
+				events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Remove})
+				delete(w.watches, path)
+				continue
+			}
+			errs = append(errs, err)
+			continue
+		}
+		events = append(events, diffSnapshots(previous, current)...)
+		w.watches[path] = current
+	}
+	return events, errs
+}
+
+// diffSnapshots 比较两次快照并生成与 inotify 一致的事件
+// 事件按名称排序，重命名产生的新名称排在其他新建文件之前，与 inotify 的事件顺序一致
+func diffSnapshots(previous, current map[string]fileSnapshot) []fsnotify.Event {
+	replaced := func(name string) bool {
+		old, ok := previous[name]
+		if !ok {
+			return true
+		}
+		snap := current[name]
+		return snap.id.valid() && old.id != snap.id
+	}
+
+	// 按 inode 识别重命名
+	created := make(map[fileID]string)
+	for _, name := range sortedNames(current) {
+		if snap := current[name]; snap.id.valid() && replaced(name) {
+			created[snap.id] = name
+		}
+	}
+
+	var events, renamedTo, others []fsnotify.Event
+	for _, name := range sortedNames(previous) {
+		old := previous[name]
+		if snap, ok := current[name]; ok && (!snap.id.valid() || old.id == snap.id) {
+			continue
+		}
+		if target, ok := created[old.id]; ok && old.id.valid() {
+			events = append(events, fsnotify.Event{Name: name, Op: fsnotify.Rename})
+			renamedTo = append(renamedTo, fsnotify.Event{Name: target, Op: fsnotify.Create})
+			delete(created, old.id)
+		} else if _, ok := current[name]; !ok {
+			events = append(events, fsnotify.Event{Name: name, Op: fsnotify.Remove})
+		}
+	}
+
+	for _, name := range sortedNames(current) {
+		snap := current[name]
+		switch {
+		case replaced(name):
+			// 重命名的目标已在上面生成事件，剩下的是真正新建的文件
+			if _, ok := created[snap.id]; ok || !snap.id.valid() {
+				others = append(others, fsnotify.Event{Name: name, Op: fsnotify.Create})
+			}
+		case !snap.isDir && snap.changed(previous[name]):
+			others = append(others, fsnotify.Event{Name: name, Op: fsnotify.Write})
+		}
+	}
+
+	events = append(events, renamedTo...)
+	return append(events, others...)
+}
+
+// sortedNames 返回排序后的路径列表
+func sortedNames(snapshot map[string]fileSnapshot) []string {
+	names := make([]string, 0, len(snapshot))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// takeSnapshot 记录路径的当前状态，目录记录其下所有条目
+func takeSnapshot(path string) (map[string]fileSnapshot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return map[string]fileSnapshot{path: newSnapshot(info)}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make(map[string]fileSnapshot, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		snapshot[filepath.Join(path, entry.Name())] = newSnapshot(info)
+	}
+	return snapshot, nil
+}
+
+// newSnapshot 根据文件信息创建快照
+func newSnapshot(info os.FileInfo) fileSnapshot {
+	return fileSnapshot{
+		id:      fileIdentity(info),
+		size:    info.Size(),
+		modTime: info.ModTime(),
+		isDir:   info.IsDir(),
+	}
+}
@@ -0,0 +1,99 @@
+package monitor
+
+import (
+	"sync"
+
+	"ClamGuardian/internal/metrics"
+	"github.com/fsnotify/fsnotify"
+)
+
+// 并发处理的默认值
+const (
+	defaultWorkers = 4
+	readBudget     = 1 << 20 // 每次处理单个文件最多读取的字节数，超过后让出给其他文件
+)
+
+// fileQueue 按文件排队的事件
+// 同一文件的事件按到达顺序由同一时间内的一个 worker 处理，不同文件之间并行
+// 连续的写入事件合并为一次读取
+type fileQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending map[string][]fsnotify.Op // 每个文件等待处理的事件
+	active  map[string]bool          // 已在就绪队列中或正在处理的文件
+	ready   []string                 // 等待 worker 处理的文件
+	closed  bool
+}
+
+// newFileQueue 创建事件队列
+func newFileQueue() *fileQueue {
+	q := &fileQueue{
+		pending: make(map[string][]fsnotify.Op),
+		active:  make(map[string]bool),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push 加入一个文件事件
+// 读取总是到文件末尾，因此紧跟在写入或创建之后的写入事件无需重复处理
+func (q *fileQueue) push(name string, op fsnotify.Op) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+	ops := q.pending[name]
+	if op == fsnotify.Write && len(ops) > 0 && (ops[len(ops)-1] == fsnotify.Write || ops[len(ops)-1] == fsnotify.Create) {
+		metrics.CoalescedEvents.Inc()
+		return
+	}
+	q.pending[name] = append(ops, op)
+
+	if !q.active[name] {
+		q.active[name] = true
+		q.ready = append(q.ready, name)
+		q.cond.Signal()
+	}
+}
+
+// pop 取出下一个待处理的文件及其全部事件，队列关闭后返回 false
+func (q *fileQueue) pop() (string, []fsnotify.Op, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.ready) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return "", nil, false
+	}
+	name := q.ready[0]
+	q.ready = q.ready[1:]
+	ops := q.pending[name]
+	delete(q.pending, name)
+	return name, ops, true
+}
+
+// done 文件的事件处理完毕，处理期间又有新事件时重新排到队尾
+func (q *fileQueue) done(name string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.pending[name]) > 0 && !q.closed {
+		q.ready = append(q.ready, name)
+		q.cond.Signal()
+		return
+	}
+	delete(q.active, name)
+}
+
+// close 关闭队列，尚未处理的事件被丢弃
+func (q *fileQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
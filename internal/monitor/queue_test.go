@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestFileQueueCoalesce(t *testing.T) {
+	tests := []struct {
+		name string
+		push []fsnotify.Op
+		want []fsnotify.Op
+	}{
+		{"writes", []fsnotify.Op{fsnotify.Write, fsnotify.Write, fsnotify.Write}, []fsnotify.Op{fsnotify.Write}},
+		{"write after create", []fsnotify.Op{fsnotify.Create, fsnotify.Write}, []fsnotify.Op{fsnotify.Create}},
+		{"write after rename", []fsnotify.Op{fsnotify.Write, fsnotify.Rename, fsnotify.Write}, []fsnotify.Op{fsnotify.Write, fsnotify.Rename, fsnotify.Write}},
+		{"write after remove", []fsnotify.Op{fsnotify.Remove, fsnotify.Write, fsnotify.Write}, []fsnotify.Op{fsnotify.Remove, fsnotify.Write}},
+		{"create not coalesced", []fsnotify.Op{fsnotify.Write, fsnotify.Create}, []fsnotify.Op{fsnotify.Write, fsnotify.Create}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newFileQueue()
+			for _, op := range tt.push {
+				q.push("a.log", op)
+			}
+			name, ops, ok := q.pop()
+			if !ok || name != "a.log" {
+				t.Fatalf("pop() = %q, %v", name, ok)
+			}
+			if fmt.Sprint(ops) != fmt.Sprint(tt.want) {
+				t.Errorf("事件为 %v，应为 %v", ops, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileQueueOrder(t *testing.T) {
+	q := newFileQueue()
+	q.push("a.log", fsnotify.Write)
+	q.push("b.log", fsnotify.Write)
+	q.push("a.log", fsnotify.Rename)
+
+	name, ops, _ := q.pop()
+	if name != "a.log" || fmt.Sprint(ops) != fmt.Sprint([]fsnotify.Op{fsnotify.Write, fsnotify.Rename}) {
+		t.Fatalf("第一次 pop() = %q %v，应为 a.log 的全部事件", name, ops)
+	}
+
+	// 处理期间同一文件的新事件等到处理完毕后排到队尾，不会交给其他 worker
+	q.push("a.log", fsnotify.Create)
+	name, ops, _ = q.pop()
+	if name != "b.log" || fmt.Sprint(ops) != fmt.Sprint([]fsnotify.Op{fsnotify.Write}) {
+		t.Fatalf("第二次 pop() = %q %v，应为 b.log", name, ops)
+	}
+	q.done("b.log")
+	q.done("a.log")
+
+	name, ops, _ = q.pop()
+	if name != "a.log" || fmt.Sprint(ops) != fmt.Sprint([]fsnotify.Op{fsnotify.Create}) {
+		t.Fatalf("第三次 pop() = %q %v，应为 a.log 的新事件", name, ops)
+	}
+	q.done("a.log")
+
+	q.close()
+	if _, _, ok := q.pop(); ok {
+		t.Error("队列关闭后 pop() 应返回 false")
+	}
+}
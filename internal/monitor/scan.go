@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"fmt"
+
+	"ClamGuardian/internal/logger"
+	"ClamGuardian/internal/metrics"
+	"ClamGuardian/internal/reader"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// 启动时没有保存位置的文件的处理策略
+const (
+	StartAtBeginning = "beginning" // 从文件开头读取
+	StartAtEnd       = "end"       // 从文件末尾开始，只处理之后写入的内容
+	StartAtStored    = "stored"    // 启动时不处理，等到文件下次写入
+)
+
+// ValidStartAt 判断 start_at 配置是否有效
+func ValidStartAt(startAt string) bool {
+	switch startAt {
+	case StartAtBeginning, StartAtEnd, StartAtStored:
+		return true
+	}
+	return false
+}
+
+// scan 处理启动前已存在的匹配文件
+// 有保存位置的文件先处理，以便轮转后的旧文件能通过原文件的记录识别出来
+func (m *Monitor) scan(files []string) {
+	var resumed, fresh []string
+	for _, filename := range files {
+		if !m.matchFile(filename) {
+			continue
+		}
+		if _, ok := m.store.GetState(filename); ok {
+			resumed = append(resumed, filename)
+		} else {
+			fresh = append(fresh, filename)
+		}
+	}
+
+	logger.Logger.Info("扫描已存在的文件",
+		zap.Int("resumed", len(resumed)),
+		zap.Int("new", len(fresh)))
+
+	for _, filename := range resumed {
+		m.enqueue(filename)
+		metrics.ProcessedFiles.Inc()
+	}
+	for _, filename := range fresh {
+		// 处理已恢复的文件时可能已经跟踪了轮转后的旧文件
+		m.mu.RLock()
+		_, ok := m.tailers[filename]
+		m.mu.RUnlock()
+		if ok {
+			continue
+		}
+		m.scanNewFile(filename)
+	}
+}
+
+// enqueue 开始跟踪文件并排队读取
+// 跟踪在扫描时按顺序完成，轮转识别依赖先处理有保存位置的文件；读取交给 worker 并行进行
+func (m *Monitor) enqueue(filename string) {
+	if !reader.IsCompressed(filename) {
+		m.mu.Lock()
+		_, ok := m.tailers[filename]
+		if !ok {
+			t, err := m.track(filename)
+			if err != nil {
+				m.mu.Unlock()
+				logger.Logger.Error("处理文件失败", zap.Error(err))
+				return
+			}
+			if info, err := t.file.Stat(); err == nil && t.probe {
+				m.probeCopy(t, info.Size())
+			}
+		}
+		m.mu.Unlock()
+	}
+	m.queue.push(filename, fsnotify.Write)
+}
+
+// scanNewFile 按 start_at 策略处理没有保存位置的文件
+func (m *Monitor) scanNewFile(filename string) {
+	startAt := StartAtBeginning
+	if root := m.rootFor(filename); root != nil && root.StartAt != "" {
+		startAt = root.StartAt
+	}
+
+	switch {
+	case startAt == StartAtStored:
+		return
+	case startAt == StartAtEnd && reader.IsCompressed(filename):
+		m.skipArchive(filename)
+	case startAt == StartAtEnd:
+		if err := m.trackAtEnd(filename); err != nil {
+			logger.Logger.Error("处理文件失败", zap.Error(err))
+			return
+		}
+	default:
+		m.enqueue(filename)
+	}
+	metrics.ProcessedFiles.Inc()
+}
+
+// trackAtEnd 开始跟踪文件，跳过已有的内容
+func (m *Monitor) trackAtEnd(filename string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, err := m.track(filename)
+	if err != nil {
+		return err
+	}
+	info, err := t.file.Stat()
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	t.offset = info.Size()
+	t.probe = false
+	m.saveState(t)
+	return nil
+}
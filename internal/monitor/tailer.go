@@ -0,0 +1,642 @@
+package monitor
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"ClamGuardian/internal/logger"
+	"ClamGuardian/internal/position"
+	"ClamGuardian/internal/reader"
+	"ClamGuardian/internal/source"
+	"go.uber.org/zap"
+)
+
+// fingerprintSize 头部指纹使用的最大字节数
+const fingerprintSize = 1024
+
+// finishedRetention 被轮转的文件结束跟踪后等待以新名称出现的时长
+const finishedRetention = time.Hour
+
+// fileID 文件的唯一标识
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// valid 当前平台是否能提供 inode 信息
+func (id fileID) valid() bool {
+	return id.ino != 0
+}
+
+// tailer 跟踪单个文件的读取状态
+// 文件句柄在轮转后依然有效，因此被重命名的文件可以继续读到末尾
+// 读取文件时只持有 mu；持有监控器写锁访问其他路径的跟踪器时也需要持有 mu，它可能正在被读取
+type tailer struct {
+	mu     sync.Mutex
+	closed bool
+	path   string
+	file   *os.File
+	id     fileID
+	offset int64
+	fp     string // 头部指纹
+	fpSize int64  // 指纹覆盖的字节数
+	probe  bool   // 新文件尚未确认是否为其他文件的副本
+	asm    *reader.Assembler
+	lines  reader.LineOptions // 按行读取的配置，编码可能由文件开头的 BOM 决定
+	bom    bool               // 是否已检查过文件开头的 BOM
+	// 轮转后的文件，读出的内容作为补读的内容交出
+	backfill bool
+
+	// 末尾未写完的行最早被发现的时间，没有时为零值
+	partialSince time.Time
+
+	// 截断前的头部指纹和读取位置，用于识别 copytruncate 产生的副本
+	prev *position.FileState
+}
+
+// openTailer 打开文件并创建跟踪器
+func openTailer(filename string) (*tailer, os.FileInfo, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("获取文件信息失败: %v", err)
+	}
+
+	t := &tailer{
+		path: filename,
+		file: file,
+		id:   fileIdentity(info),
+	}
+	t.updateFingerprint(info.Size())
+	return t, info, nil
+}
+
+// close 关闭文件句柄
+func (t *tailer) close() {
+	t.closed = true
+	t.file.Close()
+}
+
+// finish 文件不会再有新内容时结束跟踪，未结束的多行事件立即输出
+func (t *tailer) finish() {
+	t.asm.Flush()
+	t.close()
+}
+
+// headFingerprint 计算文件前 size 字节的指纹
+func (t *tailer) headFingerprint(size int64) (string, error) {
+	buf := make([]byte, size)
+	if _, err := t.file.ReadAt(buf, 0); err != nil {
+		return "", err
+	}
+	return fingerprint(buf), nil
+}
+
+// fingerprint 计算内容指纹
+func fingerprint(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// updateFingerprint 文件增长时补全头部指纹
+func (t *tailer) updateFingerprint(size int64) {
+	if t.fpSize >= fingerprintSize || size <= t.fpSize {
+		return
+	}
+	if size > fingerprintSize {
+		size = fingerprintSize
+	}
+	if fp, err := t.headFingerprint(size); err == nil {
+		t.fp, t.fpSize = fp, size
+	}
+}
+
+// sameHead 判断文件头部是否仍与给定指纹一致
+func (t *tailer) sameHead(fp string, fpSize int64, size int64) bool {
+	if fpSize == 0 {
+		return true
+	}
+	if size < fpSize {
+		return false
+	}
+	current, err := t.headFingerprint(fpSize)
+	if err != nil {
+		// 读取失败时无法判断，按未变化处理
+		return true
+	}
+	return current == fp
+}
+
+// truncated 判断文件是否被截断（copytruncate 轮转）
+func (t *tailer) truncated(size int64) bool {
+	if size < t.offset {
+		return true
+	}
+	return !t.sameHead(t.fp, t.fpSize, size)
+}
+
+// state 转换为持久化的读取状态
+// 有未结束的多行事件时保存其起始位置，重启后重新读取这些行
+func (t *tailer) state() position.FileState {
+	offset := t.offset
+	if start, ok := t.asm.Pending(); ok {
+		offset = start
+	}
+	return position.FileState{
+		Offset:          offset,
+		Device:          t.id.dev,
+		Inode:           t.id.ino,
+		Fingerprint:     t.fp,
+		FingerprintSize: t.fpSize,
+	}
+}
+
+// read 读取文件的新内容，末尾未写完的行留到下次读取
+func (m *Monitor) read(t *tailer) error {
+	_, err := m.readTail(t, false, 0)
+	return err
+}
+
+// readLimit 与 read 相同，但单次最多读取约 readBudget 字节，more 表示还有未读取的内容
+func (m *Monitor) readLimit(t *tailer) (bool, error) {
+	return m.readTail(t, false, readBudget)
+}
+
+// drain 读取全部剩余内容，末尾未写完的行也立即处理
+// 用于写入方不会再补全该行的情况，例如文件已被删除、轮转或等待超时
+func (m *Monitor) drain(t *tailer) error {
+	_, err := m.readTail(t, true, 0)
+	return err
+}
+
+// finish 读完剩余内容后结束跟踪，需要持有监控器写锁
+func (m *Monitor) finish(t *tailer) {
+	if t.probe {
+		if info, err := t.file.Stat(); err == nil {
+			m.probeCopy(t, info.Size())
+		}
+	}
+	if err := m.drain(t); err != nil {
+		logger.Logger.Error("处理文件失败",
+			zap.String("filename", t.path),
+			zap.Error(err))
+	}
+	t.finish()
+}
+
+// finishRenamed 结束跟踪被轮转的文件并保存读取位置，文件以新名称出现时从已读取的位置继续，需要持有监控器写锁
+func (m *Monitor) finishRenamed(t *tailer) {
+	m.finish(t)
+	m.saveState(t)
+	if t.id.valid() {
+		m.finished[t.id] = finishedFile{path: t.path, state: t.state(), at: time.Now()}
+	}
+}
+
+// finishedFile 已结束跟踪的轮转文件
+type finishedFile struct {
+	path  string
+	state position.FileState
+	at    time.Time
+}
+
+// takeFinished 文件是已结束跟踪的轮转文件时返回结束时的读取状态，同时清理过期的记录
+func (m *Monitor) takeFinished(t *tailer, size int64) (position.FileState, bool) {
+	now := time.Now()
+	for id, f := range m.finished {
+		if now.Sub(f.at) > finishedRetention {
+			delete(m.finished, id)
+		}
+	}
+	f, ok := m.finished[t.id]
+	if !ok || !t.id.valid() {
+		return position.FileState{}, false
+	}
+	delete(m.finished, t.id)
+	// inode 可能已被其他文件重用
+	if f.state.Offset > size || !t.sameHead(f.state.Fingerprint, f.state.FingerprintSize, size) {
+		return position.FileState{}, false
+	}
+	logger.Logger.Info("跟踪轮转后的文件",
+		zap.String("from", f.path),
+		zap.String("to", t.path),
+		zap.Int64("offset", f.state.Offset))
+	return f.state, true
+}
+
+// readTail 从上次的位置读取文件，flush 为 true 时处理末尾未写完的行
+// limit 大于 0 时读取约 limit 字节后停止，返回值表示是否还有未读取的内容
+// 只读取本文件，不访问其他跟踪器，因此持有该跟踪器的 mu 即可调用
+func (m *Monitor) readTail(t *tailer, flush bool, limit int64) (bool, error) {
+	if t.closed {
+		return false, nil
+	}
+	info, err := t.file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	size := info.Size()
+
+	if t.truncated(size) {
+		logger.Logger.Info("检测到文件被截断，从头开始读取",
+			zap.String("filename", t.path),
+			zap.Int64("offset", t.offset),
+			zap.Int64("size", size))
+		prev := t.state()
+		t.prev = &prev
+		t.offset = 0
+		t.fp, t.fpSize = "", 0
+		t.lines, t.bom = m.linesFor(t.path), false
+	}
+	t.updateFingerprint(size)
+
+	if t.offset == size {
+		return false, nil
+	}
+	if t.offset > 0 && !t.bom {
+		t.detectBOM()
+	}
+	if _, err := t.file.Seek(t.offset, io.SeekStart); err != nil {
+		return false, fmt.Errorf("设置文件偏移量失败: %v", err)
+	}
+	var (
+		newPos int64
+		more   bool
+	)
+	if flush {
+		newPos, err = reader.ReadAll(t.file, t.offset, t.lines, t.asm)
+	} else {
+		newPos, more, err = reader.ReadLinesLimit(t.file, t.offset, limit, t.lines, t.asm)
+	}
+
+	// 记录末尾未写完的行开始等待的时间，读取位置前进后重新计时
+	switch {
+	case newPos >= size:
+		t.partialSince = time.Time{}
+	case newPos != t.offset || t.partialSince.IsZero():
+		t.partialSince = time.Now()
+	}
+	t.offset = newPos
+	return more, err
+}
+
+// detectBOM 从中间位置继续读取前，根据文件开头的 BOM 确定编码
+// 从头读取时 BOM 由 reader 识别，这里只处理之后的读取
+func (t *tailer) detectBOM() {
+	head := make([]byte, 3)
+	n, _ := t.file.ReadAt(head, 0)
+	if enc, _ := reader.SniffBOM(head[:n]); enc != "" {
+		t.lines.Encoding = enc
+	}
+	t.bom = true
+}
+
+// partialExpired 末尾未写完的行是否已等待超时
+func (m *Monitor) partialExpired(t *tailer, now time.Time) bool {
+	return !t.partialSince.IsZero() && now.Sub(t.partialSince) >= m.lines.PartialWait()
+}
+
+// probeCopy 识别 copytruncate 产生的副本，从原文件已读取的位置继续，避免重复处理
+func (m *Monitor) probeCopy(t *tailer, size int64) {
+	if t.offset != 0 {
+		t.probe = false
+		return
+	}
+	for _, other := range m.tailers {
+		if other == t {
+			continue
+		}
+		other.mu.Lock()
+		heads := []position.FileState{other.state()}
+		if other.prev != nil {
+			heads = append(heads, *other.prev)
+		}
+		other.mu.Unlock()
+		for _, head := range heads {
+			if head.FingerprintSize == 0 || size < head.FingerprintSize {
+				continue
+			}
+			if fp, err := t.headFingerprint(head.FingerprintSize); err != nil || fp != head.Fingerprint {
+				continue
+			}
+			t.offset = head.Offset
+			if t.offset > size {
+				t.offset = size
+			}
+			logger.Logger.Info("检测到轮转产生的文件副本",
+				zap.String("filename", t.path),
+				zap.String("source", other.path),
+				zap.Int64("offset", t.offset))
+			t.probe = false
+			return
+		}
+	}
+	// 文件已足够大仍未匹配到来源，确认为全新文件
+	if size >= fingerprintSize {
+		t.probe = false
+	}
+}
+
+// track 开始跟踪文件，优先从已保存的状态恢复
+func (m *Monitor) track(filename string) (*tailer, error) {
+	t, info, err := openTailer(filename)
+	if err != nil {
+		return nil, err
+	}
+	// 已在跟踪的文件以新名称出现，沿用原有的跟踪器，不能按副本从头读取
+	if m.adoptRotated(filename, t.id) {
+		t.file.Close()
+		return m.tailers[filename], nil
+	}
+	t.asm = m.assembler(t)
+	t.lines = m.linesFor(t.path)
+
+	// 已结束跟踪的轮转文件以新名称出现，从结束时的位置继续，不能再按副本识别
+	if finished, ok := m.takeFinished(t, info.Size()); ok {
+		t.offset = finished.Offset
+		m.tailers[filename] = t
+		return t, nil
+	}
+
+	state, ok := m.store.GetState(filename)
+	switch {
+	case !ok:
+		t.probe = true
+	case state.Inode != 0 && t.id.valid() && (state.Inode != t.id.ino || state.Device != t.id.dev):
+		// 文件已被轮转：如果旧文件未被跟踪（例如在停止期间轮转），先读完旧文件，新文件从头开始
+		m.recoverRotated(filename, state)
+	case state.Offset > info.Size() || !t.sameHead(state.Fingerprint, state.FingerprintSize, info.Size()):
+		logger.Logger.Info("文件内容已变化，从头开始读取",
+			zap.String("filename", filename))
+	default:
+		t.offset = state.Offset
+	}
+
+	m.tailers[filename] = t
+	return t, nil
+}
+
+// recoverRotated 在同目录下查找 inode 与记录一致的旧文件并读完剩余内容
+func (m *Monitor) recoverRotated(filename string, state position.FileState) {
+	dir := filepath.Dir(filename)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		logger.Logger.Error("读取目录失败",
+			zap.String("path", dir),
+			zap.Error(err))
+		return
+	}
+
+	for _, entry := range entries {
+		candidate := filepath.Join(dir, entry.Name())
+		if candidate == filename || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		id := fileIdentity(info)
+		if id.ino != state.Inode || id.dev != state.Device || m.tracked(id) {
+			continue
+		}
+
+		t, info, err := openTailer(candidate)
+		if err != nil {
+			logger.Logger.Error("打开轮转文件失败",
+				zap.String("filename", candidate),
+				zap.Error(err))
+			return
+		}
+		t.asm = m.assembler(t)
+		t.lines = m.linesFor(t.path)
+		t.backfill = true
+		if finished, ok := m.takeFinished(t, info.Size()); ok {
+			t.offset = finished.Offset
+		} else if state.Offset <= info.Size() && t.sameHead(state.Fingerprint, state.FingerprintSize, info.Size()) {
+			t.offset = state.Offset
+		}
+
+		logger.Logger.Info("读取轮转前的剩余内容",
+			zap.String("filename", candidate),
+			zap.Int64("offset", t.offset))
+		if err := m.read(t); err != nil {
+			logger.Logger.Error("处理轮转文件失败",
+				zap.String("filename", candidate),
+				zap.Error(err))
+		}
+
+		// 轮转后的文件名仍匹配模式时继续跟踪，否则读完即关闭
+		if m.matchFile(candidate) {
+			m.tailers[candidate] = t
+			m.saveState(t)
+		} else {
+			m.finish(t)
+		}
+		return
+	}
+}
+
+// tracked 判断文件是否已被跟踪（包括等待读完的轮转文件）
+func (m *Monitor) tracked(id fileID) bool {
+	for _, t := range m.tailers {
+		if t.id == id {
+			return true
+		}
+	}
+	for _, t := range m.rotated {
+		if t.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// retire 文件被重命名或替换时读完旧文件的剩余内容
+// 旧句柄保留到原路径出现新的写入为止，以便接收写入方重新打开文件之前追加的内容
+func (m *Monitor) retire(t *tailer) {
+	if err := m.read(t); err != nil {
+		logger.Logger.Error("处理轮转文件失败",
+			zap.String("filename", t.path),
+			zap.Error(err))
+	}
+	delete(m.tailers, t.path)
+	if old, ok := m.rotated[t.path]; ok {
+		m.finishRenamed(old)
+	}
+	t.backfill = true
+	m.rotated[t.path] = t
+	m.closePath(t.path)
+}
+
+// finishRotated 原路径出现新文件后，读完并关闭该路径上被轮转的旧文件
+func (m *Monitor) finishRotated(filename string) {
+	old, ok := m.rotated[filename]
+	if !ok {
+		return
+	}
+	delete(m.rotated, filename)
+	m.finishRenamed(old)
+}
+
+// adoptRotated 被重命名的文件以新名称出现时继续沿用原有的读取状态
+// 重命名事件可能晚于新名称的事件处理，此时旧跟踪器仍以原路径记录在 tailers 中
+func (m *Monitor) adoptRotated(filename string, id fileID) bool {
+	if !id.valid() {
+		return false
+	}
+	for oldName, t := range m.rotated {
+		if t.id == id {
+			delete(m.rotated, oldName)
+			m.adopt(t, oldName, filename)
+			return true
+		}
+	}
+	for oldName, t := range m.tailers {
+		if t.id != id || oldName == filename {
+			continue
+		}
+		// 原路径仍指向该文件时是硬链接，不是重命名
+		if info, err := os.Stat(oldName); err == nil && fileIdentity(info) == id {
+			continue
+		}
+		delete(m.tailers, oldName)
+		m.adopt(t, oldName, filename)
+		return true
+	}
+	return false
+}
+
+// adopt 以新名称继续跟踪被重命名的文件
+func (m *Monitor) adopt(t *tailer, oldName, filename string) {
+	logger.Logger.Info("跟踪轮转后的文件",
+		zap.String("from", oldName),
+		zap.String("to", filename))
+	// 仍以原路径跟踪的文件可能正在被读取
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.backfill {
+		t.backfill = true
+		m.closePath(oldName)
+	}
+	t.path = filename
+	m.tailers[filename] = t
+	m.saveState(t)
+}
+
+// assembler 按文件所属监控路径的多行配置创建合并器
+// 事件的来源路径在输出时读取，文件被重新关联后使用新的路径
+func (m *Monitor) assembler(t *tailer) *reader.Assembler {
+	return reader.NewAssembler(m.multiline(t.path), func(line string, _ int64) {
+		m.emit(t.path, line, t.backfill)
+	})
+}
+
+// linesFor 文件所属监控路径的按行读取配置
+func (m *Monitor) linesFor(filename string) reader.LineOptions {
+	lines := m.lines
+	if root := m.rootFor(filename); root != nil {
+		lines.Encoding = root.Encoding
+	}
+	return lines
+}
+
+// multiline 文件所属监控路径的多行配置，未配置时返回 nil
+func (m *Monitor) multiline(filename string) *reader.Multiline {
+	if root := m.rootFor(filename); root != nil {
+		return root.multiline
+	}
+	return nil
+}
+
+// emit 将一条事件交给文件所属监控路径的处理函数
+// 监控路径配置了解析器时，解析出的字段随事件一起交出
+func (m *Monitor) emit(path, line string, backfill bool) {
+	var fields map[string]string
+	if root := m.rootFor(path); root != nil && root.parser != nil {
+		fields = root.parser.Parse(line)
+	}
+	m.handlerFor(path).Handle(source.Event{
+		Line:   line,
+		Fields: fields,
+		Origin: source.Origin{Input: m.name, Kind: source.KindFile, Path: path, Backfill: backfill},
+	})
+}
+
+// handlerFor 文件所属监控路径的处理函数
+func (m *Monitor) handlerFor(path string) source.Handler {
+	if root := m.rootFor(path); root != nil && root.handler != nil {
+		return root.handler
+	}
+	return m.handler
+}
+
+// closePath 通知处理函数该路径上的文件已停止跟踪
+func (m *Monitor) closePath(path string) {
+	if c, ok := m.handlerFor(path).(source.PathCloser); ok {
+		c.ClosePath(path)
+	}
+}
+
+// flushExpired 输出等待超时的多行事件和末尾未写完的行
+func (m *Monitor) flushExpired() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	for _, t := range m.tailers {
+		m.flushTailer(t, now, true)
+	}
+	for _, t := range m.rotated {
+		m.flushTailer(t, now, false)
+	}
+}
+
+// flushTailer 检查单个跟踪器的超时内容，save 为 true 时保存读取位置
+func (m *Monitor) flushTailer(t *tailer, now time.Time, save bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return
+	}
+	flushed := m.flushPartial(t, now)
+	if t.asm.Expired(now) {
+		t.asm.Flush()
+		flushed = true
+	}
+	if flushed && save {
+		m.saveState(t)
+	}
+}
+
+// flushPartial 末尾未写完的行等待超时后按完整的行处理
+func (m *Monitor) flushPartial(t *tailer, now time.Time) bool {
+	if !m.partialExpired(t, now) {
+		return false
+	}
+	logger.Logger.Warn("末尾的行等待超时，按完整的行处理",
+		zap.String("filename", t.path),
+		zap.Int64("offset", t.offset))
+	if err := m.drain(t); err != nil {
+		logger.Logger.Error("处理文件失败",
+			zap.String("filename", t.path),
+			zap.Error(err))
+	}
+	return true
+}
+
+// saveState 更新位置管理器和状态管理器
+func (m *Monitor) saveState(t *tailer) {
+	m.store.UpdateState(t.path, t.state())
+}
@@ -0,0 +1,193 @@
+package monitor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"ClamGuardian/internal/logger"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// 监控后端类型
+const (
+	BackendInotify = "inotify" // 使用 fsnotify 接收内核事件
+	BackendPoll    = "poll"    // 定期检查文件状态
+	BackendAuto    = "auto"    // 网络文件系统使用轮询，其余使用 inotify
+)
+
+// ValidBackend 判断监控后端配置是否有效
+func ValidBackend(backend string) bool {
+	switch backend {
+	case BackendInotify, BackendPoll, BackendAuto:
+		return true
+	}
+	return false
+}
+
+// watcher 文件系统事件来源，所有后端产生的事件都交给 handleEvent 处理
+type watcher interface {
+	Add(path string) error
+	Remove(path string) error
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+	Close() error
+}
+
+// newWatcher 根据配置创建监控后端
+func newWatcher(backend string, interval time.Duration) (watcher, error) {
+	switch backend {
+	case "", BackendInotify:
+		return newInotifyWatcher()
+	case BackendPoll:
+		return newPollWatcher(interval), nil
+	case BackendAuto:
+		return newAutoWatcher(interval)
+	default:
+		return nil, fmt.Errorf("未知的监控后端: %s", backend)
+	}
+}
+
+// inotifyWatcher 基于 fsnotify 的监控后端
+type inotifyWatcher struct {
+	*fsnotify.Watcher
+}
+
+// newInotifyWatcher 创建 fsnotify 监控后端
+func newInotifyWatcher() (*inotifyWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &inotifyWatcher{Watcher: w}, nil
+}
+
+// Events 返回事件通道
+func (w *inotifyWatcher) Events() <-chan fsnotify.Event {
+	return w.Watcher.Events
+}
+
+// Errors 返回错误通道
+func (w *inotifyWatcher) Errors() <-chan error {
+	return w.Watcher.Errors
+}
+
+// autoWatcher 按路径所在的文件系统选择后端
+// 网络文件系统和 overlay 上收不到 inotify 事件，这些路径改用轮询
+type autoWatcher struct {
+	inotify *inotifyWatcher
+	poll    *pollWatcher
+	polled  map[string]bool
+	events  chan fsnotify.Event
+	errors  chan error
+	done    chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+}
+
+// newAutoWatcher 创建自动选择的监控后端
+func newAutoWatcher(interval time.Duration) (*autoWatcher, error) {
+	iw, err := newInotifyWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &autoWatcher{
+		inotify: iw,
+		poll:    newPollWatcher(interval),
+		polled:  make(map[string]bool),
+		events:  make(chan fsnotify.Event),
+		errors:  make(chan error),
+		done:    make(chan struct{}),
+	}
+	w.wg.Add(2)
+	go w.forward(iw.Events(), iw.Errors())
+	go w.forward(w.poll.Events(), w.poll.Errors())
+
+	// 两个后端都停止后关闭合并的通道
+	go func() {
+		w.wg.Wait()
+		close(w.events)
+		close(w.errors)
+	}()
+	return w, nil
+}
+
+// forward 将后端的事件合并到同一个通道
+func (w *autoWatcher) forward(events <-chan fsnotify.Event, errors <-chan error) {
+	defer w.wg.Done()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			select {
+			case w.events <- event:
+			case <-w.done:
+				return
+			}
+		case err, ok := <-errors:
+			if !ok {
+				return
+			}
+			select {
+			case w.errors <- err:
+			case <-w.done:
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Add 添加监控路径
+func (w *autoWatcher) Add(path string) error {
+	usePoll := isNetworkFS(path)
+
+	w.mu.Lock()
+	w.polled[path] = usePoll
+	w.mu.Unlock()
+
+	if usePoll {
+		logger.Logger.Info("路径位于网络文件系统，使用轮询监控",
+			zap.String("path", path))
+		return w.poll.Add(path)
+	}
+	return w.inotify.Add(path)
+}
+
+// Remove 移除监控路径
+func (w *autoWatcher) Remove(path string) error {
+	w.mu.Lock()
+	usePoll, ok := w.polled[path]
+	delete(w.polled, path)
+	w.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("路径未被监控: %s", path)
+	}
+	if usePoll {
+		return w.poll.Remove(path)
+	}
+	return w.inotify.Remove(path)
+}
+
+// Events 返回事件通道
+func (w *autoWatcher) Events() <-chan fsnotify.Event {
+	return w.events
+}
+
+// Errors 返回错误通道
+func (w *autoWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Close 关闭所有后端
+func (w *autoWatcher) Close() error {
+	close(w.done)
+	w.poll.Close()
+	return w.inotify.Close()
+}
@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"ClamGuardian/internal/metrics"
+)
+
+// clamd 的 LogTime 时间格式，例如 "Thu Oct 16 10:00:00 2026 -> "
+const clamdTimeLayout = "Mon Jan _2 15:04:05 2006"
+
+var (
+	clamdPrefix = regexp.MustCompile(`^([A-Z][a-z]{2} [A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2} \d{4}) -> (.*)$`)
+
+	// "文件: 特征 FOUND"，开启 ExtendedDetectionInfo 时特征后附带 "(MD5:大小)"
+	clamdFound = regexp.MustCompile(`^(.+): (\S+?)(?:\(([0-9a-fA-F]{32}):(\d+)\))? FOUND$`)
+	clamdOK    = regexp.MustCompile(`^(.+): OK$`)
+	// clamscan 对单个文件的错误，例如 "文件: Can't open file or directory ERROR"
+	clamdFileError  = regexp.MustCompile(`^(.+): (.+) ERROR$`)
+	clamdError      = regexp.MustCompile(`^(?:ERROR|LibClamAV Error)\s*:?\s*(.*)$`)
+	clamdSelfCheck  = regexp.MustCompile(`^SelfCheck: (.*)$`)
+	clamdDBReload   = regexp.MustCompile(`^(?:Reading databases from|Database correctly reloaded|Database has changed|Activating the newly loaded database|Reloading database)`)
+	clamdSignatures = regexp.MustCompile(`\((\d+) signatures\)`)
+)
+
+// ClamdParser 解析 clamd、clamscan 和 clamonacc 的日志
+// 三者对扫描结果的输出格式相同，clamd 开启 LogTime 时每行带有时间前缀
+type ClamdParser struct{}
+
+// NewClamd 创建 ClamAV 日志解析器
+func NewClamd() *ClamdParser {
+	return &ClamdParser{}
+}
+
+// Parse 解析一行日志，返回带有 event 字段的字段集合，不是已知类型时返回 nil
+func (p *ClamdParser) Parse(line string) map[string]string {
+	fields := make(map[string]string)
+	msg := strings.TrimSpace(line)
+	if m := clamdPrefix.FindStringSubmatch(msg); m != nil {
+		if t, err := time.ParseInLocation(clamdTimeLayout, m[1], time.Local); err == nil {
+			fields[FieldTime] = t.Format(time.RFC3339)
+		}
+		msg = m[2]
+	}
+
+	switch {
+	case matchInto(clamdFound, msg, fields, FieldFile, FieldSignature, FieldHash, FieldSize):
+		fields[FieldEvent] = EventDetection
+	case matchInto(clamdOK, msg, fields, FieldFile):
+		fields[FieldEvent] = EventScanOK
+	case matchInto(clamdSelfCheck, msg, fields, FieldMessage):
+		// 自检发现病毒库变化时会随即重新加载
+		fields[FieldEvent] = EventSelfCheck
+		if strings.Contains(msg, "modification detected") {
+			fields[FieldEvent] = EventDBReload
+		}
+	case clamdDBReload.MatchString(msg):
+		fields[FieldEvent] = EventDBReload
+		fields[FieldMessage] = msg
+		matchInto(clamdSignatures, msg, fields, FieldSignatures)
+	case matchInto(clamdError, msg, fields, FieldMessage),
+		matchInto(clamdFileError, msg, fields, FieldFile, FieldMessage):
+		fields[FieldEvent] = EventError
+	default:
+		return nil
+	}
+
+	metrics.ClamavEvents.WithLabelValues(fields[FieldEvent]).Inc()
+	return fields
+}
+
+// matchInto 匹配成功时把各个捕获组按顺序记录到对应字段，未参与匹配的捕获组不记录
+func matchInto(re *regexp.Regexp, msg string, fields map[string]string, names ...string) bool {
+	m := re.FindStringSubmatch(msg)
+	if m == nil {
+		return false
+	}
+	for i, name := range names {
+		if i+1 < len(m) && m[i+1] != "" {
+			fields[name] = m[i+1]
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClamdParse(t *testing.T) {
+	logTime, err := time.ParseInLocation(clamdTimeLayout, "Fri Oct 16 10:00:00 2026", time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line string
+		want map[string]string
+	}{
+		{
+			"Fri Oct 16 10:00:00 2026 -> /tmp/eicar.com: Win.Test.EICAR_HDB-1(44d88612fea8a8f36de82e1278abb02f:68) FOUND",
+			map[string]string{FieldEvent: EventDetection, FieldTime: logTime.Format(time.RFC3339),
+				FieldFile: "/tmp/eicar.com", FieldSignature: "Win.Test.EICAR_HDB-1",
+				FieldHash: "44d88612fea8a8f36de82e1278abb02f", FieldSize: "68"},
+		},
+		{
+			"/srv/share/a b: c.doc: Doc.Dropper.Agent-1 FOUND",
+			map[string]string{FieldEvent: EventDetection, FieldFile: "/srv/share/a b: c.doc", FieldSignature: "Doc.Dropper.Agent-1"},
+		},
+		{
+			"/srv/share/report.pdf: OK\r",
+			map[string]string{FieldEvent: EventScanOK, FieldFile: "/srv/share/report.pdf"},
+		},
+		{
+			"Fri Oct 16 10:00:00 2026 -> SelfCheck: Database status OK.",
+			map[string]string{FieldEvent: EventSelfCheck, FieldTime: logTime.Format(time.RFC3339), FieldMessage: "Database status OK."},
+		},
+		{
+			"SelfCheck: Database modification detected. Forcing reload.",
+			map[string]string{FieldEvent: EventDBReload, FieldMessage: "Database modification detected. Forcing reload."},
+		},
+		{
+			"Database correctly reloaded (8708182 signatures)",
+			map[string]string{FieldEvent: EventDBReload, FieldMessage: "Database correctly reloaded (8708182 signatures)", FieldSignatures: "8708182"},
+		},
+		{
+			"Reading databases from /var/lib/clamav",
+			map[string]string{FieldEvent: EventDBReload, FieldMessage: "Reading databases from /var/lib/clamav"},
+		},
+		{
+			"ERROR: Can't connect to clamd: No such file or directory",
+			map[string]string{FieldEvent: EventError, FieldMessage: "Can't connect to clamd: No such file or directory"},
+		},
+		{
+			"LibClamAV Error: cli_loaddb(): No supported database files found in /var/lib/clamav",
+			map[string]string{FieldEvent: EventError, FieldMessage: "cli_loaddb(): No supported database files found in /var/lib/clamav"},
+		},
+		{
+			"/root/secret: Can't open file or directory ERROR",
+			map[string]string{FieldEvent: EventError, FieldFile: "/root/secret", FieldMessage: "Can't open file or directory"},
+		},
+		{"Limits: Global time limit set to 120000 milliseconds.", nil},
+		{"Fri Oct 16 10:00:00 2026 -> +++ Started at Fri Oct 16 10:00:00 2026", nil},
+		{"", nil},
+	}
+	p := NewClamd()
+	for _, tt := range tests {
+		if got := p.Parse(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %v，应为 %v", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package parser
+
+import "fmt"
+
+// 内置解析器名称
+const (
+	Clamd = "clamd" // clamd、clamscan 和 clamonacc 的日志
+)
+
+// 解析后的事件类型，记录在事件的 event 字段中
+const (
+	EventDetection = "detection"  // 发现病毒
+	EventScanOK    = "scan-ok"    // 文件扫描通过
+	EventDBReload  = "db-reload"  // 病毒库重新加载
+	EventSelfCheck = "self-check" // 病毒库状态自检
+	EventError     = "error"      // 扫描或运行错误
+)
+
+// 解析器输出的字段名
+const (
+	FieldEvent      = "event"      // 事件类型
+	FieldTime       = "log_time"   // 日志行自带的时间，RFC 3339 格式
+	FieldFile       = "file"       // 被扫描的文件
+	FieldSignature  = "signature"  // 病毒特征名
+	FieldHash       = "hash"       // 文件的 MD5，需要开启 ExtendedDetectionInfo
+	FieldSize       = "size"       // 文件大小，需要开启 ExtendedDetectionInfo
+	FieldSignatures = "signatures" // 加载的特征数
+	FieldMessage    = "message"    // 去掉时间前缀后的消息
+)
+
+// Parser 将一行日志解析为字段，无法识别的行返回 nil
+type Parser interface {
+	Parse(line string) map[string]string
+}
+
+// Valid 检查解析器名称是否有效，空字符串表示不解析
+func Valid(name string) bool {
+	return name == "" || name == Clamd
+}
+
+// ValidEvent 检查事件类型是否有效
+func ValidEvent(event string) bool {
+	switch event {
+	case EventDetection, EventScanOK, EventDBReload, EventSelfCheck, EventError:
+		return true
+	}
+	return false
+}
+
+// New 按名称创建解析器，名称为空时返回 nil
+func New(name string) (Parser, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case Clamd:
+		return NewClamd(), nil
+	}
+	return nil, fmt.Errorf("未知的解析器: %s", name)
+}
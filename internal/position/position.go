@@ -11,9 +11,29 @@ import (
 	"go.uber.org/zap"
 )
 
+// FileState 文件读取状态
+// 除读取位置外还记录文件的设备号、inode 和头部指纹，用于识别日志轮转
+type FileState struct {
+	Offset          int64  `json:"offset"`
+	Device          uint64 `json:"device,omitempty"`
+	Inode           uint64 `json:"inode,omitempty"`
+	Fingerprint     string `json:"fingerprint,omitempty"`
+	FingerprintSize int64  `json:"fingerprint_size,omitempty"`
+
+	// 压缩归档的偏移量和指纹均按解压后的内容计算
+	Compressed bool `json:"compressed,omitempty"`
+	Completed  bool `json:"completed,omitempty"` // 归档已完整处理
+
+	// 文件被删除的时间，删除后的记录保留一段时间，用于识别压缩后的轮转文件
+	RemovedAt int64 `json:"removed_at,omitempty"`
+}
+
+// removedRetention 已删除文件的记录保留时长
+const removedRetention = 7 * 24 * time.Hour
+
 // Manager 位置管理器
 type Manager struct {
-	positions   map[string]int64
+	positions   map[string]*FileState
 	storePath   string
 	mu          sync.RWMutex
 	updateTimer *time.Timer
@@ -22,7 +42,7 @@ type Manager struct {
 // NewManager 创建新的位置管理器
 func NewManager(storePath string, updateInterval int) (*Manager, error) {
 	m := &Manager{
-		positions: make(map[string]int64),
+		positions: make(map[string]*FileState),
 		storePath: storePath,
 	}
 
@@ -36,18 +56,43 @@ func NewManager(storePath string, updateInterval int) (*Manager, error) {
 	return m, nil
 }
 
-// GetPosition 获取文件位置
-func (m *Manager) GetPosition(filename string) int64 {
+// GetState 获取文件的完整读取状态
+func (m *Manager) GetState(filename string) (FileState, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.positions[filename]
+	state, ok := m.positions[filename]
+	if !ok || state.RemovedAt != 0 {
+		return FileState{}, false
+	}
+	return *state, true
 }
 
-// UpdatePosition 更新文件位置
-func (m *Manager) UpdatePosition(filename string, position int64) {
+// UpdateState 更新文件的完整读取状态
+func (m *Manager) UpdateState(filename string, state FileState) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.positions[filename] = position
+	state.RemovedAt = 0
+	m.positions[filename] = &state
+}
+
+// RetirePosition 标记文件已被删除，记录保留一段时间供 Snapshot 查询
+func (m *Manager) RetirePosition(filename string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if state, ok := m.positions[filename]; ok {
+		state.RemovedAt = time.Now().Unix()
+	}
+}
+
+// Snapshot 获取所有记录的副本，包括已删除文件的记录
+func (m *Manager) Snapshot() map[string]FileState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	states := make(map[string]FileState, len(m.positions))
+	for filename, state := range m.positions {
+		states[filename] = *state
+	}
+	return states
 }
 
 // load 从磁盘加载位置信息
@@ -66,8 +111,16 @@ func (m *Manager) load() error {
 	}
 
 	if err := json.Unmarshal(data, &m.positions); err != nil {
-		logger.Logger.Error("解析位置文件失败", zap.Error(err))
-		return err
+		// 兼容旧格式：文件名到偏移量的映射
+		var legacy map[string]int64
+		if legacyErr := json.Unmarshal(data, &legacy); legacyErr != nil {
+			logger.Logger.Error("解析位置文件失败", zap.Error(err))
+			return err
+		}
+		m.positions = make(map[string]*FileState, len(legacy))
+		for filename, offset := range legacy {
+			m.positions[filename] = &FileState{Offset: offset}
+		}
 	}
 
 	logger.Logger.Info("成功加载位置信息",
@@ -77,9 +130,16 @@ func (m *Manager) load() error {
 
 // save 保存位置信息到磁盘
 func (m *Manager) save() error {
-	m.mu.RLock()
+	m.mu.Lock()
+	// 清理过期的删除记录
+	expire := time.Now().Add(-removedRetention).Unix()
+	for filename, state := range m.positions {
+		if state.RemovedAt != 0 && state.RemovedAt < expire {
+			delete(m.positions, filename)
+		}
+	}
 	data, err := json.Marshal(m.positions)
-	m.mu.RUnlock()
+	m.mu.Unlock()
 
 	if err != nil {
 		return fmt.Errorf("序列化位置信息失败: %v", err)
@@ -100,13 +160,6 @@ func (m *Manager) periodicUpdate() {
 	}
 }
 
-// RemovePosition 移除文件位置记录
-func (m *Manager) RemovePosition(filename string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	delete(m.positions, filename)
-}
-
 // FilePosition 文件位置信息
 type FilePosition struct {
 	Filename string
@@ -120,14 +173,17 @@ func (m *Manager) GetAllPositions() []FilePosition {
 	defer m.mu.RUnlock()
 
 	positions := make([]FilePosition, 0, len(m.positions))
-	for filename, pos := range m.positions {
+	for filename, state := range m.positions {
+		if state.RemovedAt != 0 {
+			continue
+		}
 		fileInfo, err := os.Stat(filename)
 		if err != nil {
 			continue // 跳过无法访问的文件
 		}
 		positions = append(positions, FilePosition{
 			Filename: filename,
-			Position: pos,
+			Position: state.Offset,
 			FileSize: fileInfo.Size(),
 		})
 	}
@@ -0,0 +1,88 @@
+package reader
+
+import (
+	"bytes"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// 支持的字符编码，读取时解码为 UTF-8 后再参与匹配
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingGBK     = "gbk"
+	EncodingGB18030 = "gb18030"
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+)
+
+// ValidEncoding 检查字符编码是否受支持，空字符串表示 UTF-8
+func ValidEncoding(name string) bool {
+	switch name {
+	case "", EncodingUTF8, EncodingGBK, EncodingGB18030, EncodingUTF16LE, EncodingUTF16BE:
+		return true
+	}
+	return false
+}
+
+// 各编码的 BOM
+var boms = []struct {
+	encoding string
+	mark     []byte
+}{
+	{EncodingUTF8, []byte{0xEF, 0xBB, 0xBF}},
+	{EncodingUTF16LE, []byte{0xFF, 0xFE}},
+	{EncodingUTF16BE, []byte{0xFE, 0xFF}},
+}
+
+// SniffBOM 检查文件开头的 BOM，返回对应的编码和 BOM 的长度，没有 BOM 时返回空字符串
+func SniffBOM(head []byte) (string, int) {
+	for _, bom := range boms {
+		if bytes.HasPrefix(head, bom.mark) {
+			return bom.encoding, len(bom.mark)
+		}
+	}
+	return "", 0
+}
+
+// decoder 将一行的原始字节解码为 UTF-8
+type decoder struct {
+	name string
+	dec  *encoding.Decoder // UTF-8 时为 nil
+}
+
+// newDecoder 创建解码器，未知的编码按 UTF-8 处理（配置加载时已检查）
+func newDecoder(name string) decoder {
+	var enc encoding.Encoding
+	switch name {
+	case EncodingGBK:
+		enc = simplifiedchinese.GBK
+	case EncodingGB18030:
+		enc = simplifiedchinese.GB18030
+	case EncodingUTF16LE:
+		enc = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case EncodingUTF16BE:
+		enc = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	default:
+		return decoder{name: EncodingUTF8}
+	}
+	return decoder{name: name, dec: enc.NewDecoder()}
+}
+
+// wide 是否为两个字节一个编码单元的编码，此时换行符占两个字节
+func (d decoder) wide() bool {
+	return d.name == EncodingUTF16LE || d.name == EncodingUTF16BE
+}
+
+// decode 解码一行，无法解码的字节替换为 U+FFFD
+func (d decoder) decode(data []byte) string {
+	if d.dec == nil {
+		return string(data)
+	}
+	out, err := d.dec.Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
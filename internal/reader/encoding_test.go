@@ -0,0 +1,160 @@
+package reader
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// encode 按编码转换测试内容
+func encode(t *testing.T, enc encoding.Encoding, s string) string {
+	t.Helper()
+	out, err := enc.NewEncoder().String(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestSniffBOM(t *testing.T) {
+	tests := []struct {
+		head string
+		enc  string
+		n    int
+	}{
+		{"\xef\xbb\xbfabc", EncodingUTF8, 3},
+		{"\xff\xfea\x00", EncodingUTF16LE, 2},
+		{"\xfe\xff\x00a", EncodingUTF16BE, 2},
+		{"\xff", "", 0},
+		{"abc", "", 0},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		if enc, n := SniffBOM([]byte(tt.head)); enc != tt.enc || n != tt.n {
+			t.Errorf("SniffBOM(%q) = %q, %d，应为 %q, %d", tt.head, enc, n, tt.enc, tt.n)
+		}
+	}
+}
+
+func TestReadLinesUTF16(t *testing.T) {
+	le := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	be := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	// "上" 的编码为 0x4E0A，小端序时 0x0A 位于编码单元的第一个字节，不是换行符
+	const text = "病毒上报 FOUND"
+
+	tests := []struct {
+		name     string
+		encoding string
+		content  string
+		want     []string
+	}{
+		{"LE with BOM", "", "\xff\xfe" + encode(t, le, text+"\nok\n"), []string{text + "@24", "ok@30"}},
+		{"LE with BOM and CRLF", "", "\xff\xfe" + encode(t, le, text+"\r\nok\r\n"), []string{text + "@26", "ok@34"}},
+		{"BE with BOM", "", "\xfe\xff" + encode(t, be, text+"\nok\n"), []string{text + "@24", "ok@30"}},
+		{"BE with BOM and CRLF", "", "\xfe\xff" + encode(t, be, text+"\r\nok\r\n"), []string{text + "@26", "ok@34"}},
+		{"LE configured", EncodingUTF16LE, encode(t, le, text+"\r\nok\r\n"), []string{text + "@24", "ok@32"}},
+		{"BE configured", EncodingUTF16BE, encode(t, be, text+"\r\nok\r\n"), []string{text + "@24", "ok@32"}},
+		{"BOM overrides configured", EncodingGBK, "\xff\xfe" + encode(t, le, "ok\n"), []string{"ok@8"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, oneByte := range []bool{false, true} {
+				lines, pos := readAt(t, tt.content, 0, LineOptions{MaxLineSize: 64, Encoding: tt.encoding}, false, oneByte)
+				if fmt.Sprint(lines) != fmt.Sprint(tt.want) || pos != int64(len(tt.content)) {
+					t.Errorf("oneByte=%v: 读取到 %q，位置 %d，应为 %q，位置 %d", oneByte, lines, pos, tt.want, len(tt.content))
+				}
+			}
+		})
+	}
+}
+
+func TestReadLinesUTF16SplitNewline(t *testing.T) {
+	for _, tc := range []struct {
+		encoding string
+		enc      encoding.Encoding
+	}{
+		{EncodingUTF16LE, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)},
+		{EncodingUTF16BE, unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)},
+	} {
+		t.Run(tc.encoding, func(t *testing.T) {
+			content := encode(t, tc.enc, "上报\r\nok\r\n")
+			opts := LineOptions{MaxLineSize: 64, Encoding: tc.encoding}
+			// 每次写入停在换行符中间的任意位置，之后从返回的位置继续读取
+			for cut := 1; cut < len(content); cut++ {
+				lines, pos := readAt(t, content[:cut], 0, opts, false, false)
+				rest, end := readAt(t, content, pos, opts, false, false)
+				got := fmt.Sprint(append(lines, rest...))
+				if got != "[上报@8 ok@16]" || end != int64(len(content)) {
+					t.Errorf("在 %d 处中断: 读取到 %s，位置 %d", cut, got, end)
+				}
+			}
+		})
+	}
+}
+
+func TestReadLinesUTF16LongLines(t *testing.T) {
+	le := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	be := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	// 单行最大 16 字节，即 8 个 UTF-16 编码单元，换行符不计入长度
+	tests := []struct {
+		name     string
+		encoding string
+		content  string
+		policy   string
+		want     []string
+	}{
+		{"LE exact", EncodingUTF16LE, encode(t, le, "aaaaaaaa\r\nx\n"), LongLineSkip, []string{"aaaaaaaa@20", "x@24"}},
+		{"BE exact", EncodingUTF16BE, encode(t, be, "aaaaaaaa\r\nx\n"), LongLineSkip, []string{"aaaaaaaa@20", "x@24"}},
+		{"LE over truncate", EncodingUTF16LE, encode(t, le, "aaaaaaaab\nx\n"), LongLineTruncate, []string{"aaaaaaaa@20", "x@24"}},
+		{"BE over split", EncodingUTF16BE, encode(t, be, "aaaaaaaab\r\nx\n"), LongLineSplit, []string{"aaaaaaaa@16", "b@22", "x@26"}},
+		{"LE over skip", EncodingUTF16LE, encode(t, le, "aaaaaaaab\nx\n"), LongLineSkip, []string{"x@24"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, oneByte := range []bool{false, true} {
+				opts := LineOptions{MaxLineSize: 16, LongLines: tt.policy, Encoding: tt.encoding}
+				lines, pos := readAt(t, tt.content, 0, opts, false, oneByte)
+				if fmt.Sprint(lines) != fmt.Sprint(tt.want) || pos != int64(len(tt.content)) {
+					t.Errorf("oneByte=%v: 读取到 %q，位置 %d，应为 %q，位置 %d", oneByte, lines, pos, tt.want, len(tt.content))
+				}
+			}
+		})
+	}
+}
+
+func TestReadLinesGBK(t *testing.T) {
+	const text = "发现病毒 Win.Trojan.Agent FOUND"
+	tests := []struct {
+		name     string
+		encoding string
+		enc      encoding.Encoding
+	}{
+		{"gbk", EncodingGBK, simplifiedchinese.GBK},
+		{"gb18030", EncodingGB18030, simplifiedchinese.GB18030},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := encode(t, tt.enc, text)
+			content := line + "\r\nok\n"
+			lines, pos := readAt(t, content, 0, LineOptions{MaxLineSize: 64, Encoding: tt.encoding}, false, true)
+			want := []string{fmt.Sprintf("%s@%d", text, len(line)+2), fmt.Sprintf("ok@%d", len(content))}
+			if fmt.Sprint(lines) != fmt.Sprint(want) || pos != int64(len(content)) {
+				t.Errorf("读取到 %q，位置 %d，应为 %q", lines, pos, want)
+			}
+		})
+	}
+
+	// GB18030 的四字节编码
+	content := encode(t, simplifiedchinese.GB18030, "€😀") + "\n"
+	if lines, _ := readAt(t, content, 0, LineOptions{MaxLineSize: 64, Encoding: EncodingGB18030}, false, false); fmt.Sprint(lines) != fmt.Sprintf("[€😀@%d]", len(content)) {
+		t.Errorf("GB18030 四字节编码读取到 %q", lines)
+	}
+
+	// 未按配置编码的内容不能解码时替换为 U+FFFD，读取仍然前进
+	if lines, pos := readAt(t, "\x81\n", 0, LineOptions{MaxLineSize: 64, Encoding: EncodingGBK}, false, false); fmt.Sprint(lines) != "[�@2]" || pos != 2 {
+		t.Errorf("无效的 GBK 内容读取到 %q，位置 %d", lines, pos)
+	}
+}
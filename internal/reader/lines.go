@@ -0,0 +1,296 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"ClamGuardian/internal/metrics"
+)
+
+// 超长行的处理策略
+const (
+	LongLineTruncate = "truncate" // 只保留前面的部分参与匹配
+	LongLineSplit    = "split"    // 按最大长度拆分为多行
+	LongLineSkip     = "skip"     // 丢弃整行
+)
+
+// minLineSize 单行最大长度的下限
+const minLineSize = 16
+
+// ValidLongLine 检查超长行策略是否有效
+func ValidLongLine(policy string) bool {
+	switch policy {
+	case LongLineTruncate, LongLineSplit, LongLineSkip:
+		return true
+	}
+	return false
+}
+
+// defaultPartialTimeout 末尾不完整的行的默认等待时间
+const defaultPartialTimeout = 5 * time.Second
+
+// LineOptions 按行读取的配置
+type LineOptions struct {
+	MaxLineSize    int           // 单行最大字节数
+	LongLines      string        // 超长行的处理策略，默认截断
+	PartialTimeout time.Duration // 末尾不完整的行等待写完的最长时间
+	Encoding       string        // 字符编码，默认 UTF-8；文件开头有 BOM 时以 BOM 为准
+}
+
+// PartialWait 末尾不完整的行等待写完的时间
+func (o LineOptions) PartialWait() time.Duration {
+	if o.PartialTimeout <= 0 {
+		return defaultPartialTimeout
+	}
+	return o.PartialTimeout
+}
+
+// lineReader 按行读取的状态
+type lineReader struct {
+	opts      LineOptions
+	asm       *Assembler
+	dec       decoder
+	line      []byte // 当前行已保留的内容（原始字节）
+	last      byte   // 上一次读取到的最后一个字节
+	start     int64  // 当前行（或拆分后剩余部分）起始位置的文件偏移量
+	lineStart int64  // 当前行起始位置的文件偏移量
+	long      bool   // 当前行是否超长
+}
+
+// ReadLines 从 r 的当前位置逐行读取并交给 asm，offset 为 r 当前位置对应的文件偏移量
+// 每行按配置的编码解码为 UTF-8，偏移量始终为原始字节位置
+// 超过最大长度的行按策略截断、拆分或丢弃，读取位置总是越过该行
+// 末尾没有换行的内容可能尚未写完，不做处理，返回的偏移量为其起始位置
+// 出错时返回已处理到的位置
+func ReadLines(r io.Reader, offset int64, opts LineOptions, asm *Assembler) (int64, error) {
+	pos, _, err := readLines(r, offset, 0, opts, asm, false)
+	return pos, err
+}
+
+// ReadLinesLimit 与 ReadLines 相同，但读取超过 limit 字节后在下一个行尾停止
+// more 表示是否因达到限制而提前停止，调用方可以稍后从返回的位置继续读取
+func ReadLinesLimit(r io.Reader, offset, limit int64, opts LineOptions, asm *Assembler) (pos int64, more bool, err error) {
+	return readLines(r, offset, limit, opts, asm, false)
+}
+
+// ReadAll 与 ReadLines 相同，但末尾没有换行的内容也作为最后一行处理
+// 用于写入方已经结束的文件
+func ReadAll(r io.Reader, offset int64, opts LineOptions, asm *Assembler) (int64, error) {
+	pos, _, err := readLines(r, offset, 0, opts, asm, true)
+	return pos, err
+}
+
+// readLines 按行读取，limit 大于 0 时读取超过 limit 字节后在行尾停止
+// flush 为 true 时处理末尾不完整的行
+func readLines(r io.Reader, offset, limit int64, opts LineOptions, asm *Assembler, flush bool) (int64, bool, error) {
+	if opts.MaxLineSize < minLineSize {
+		opts.MaxLineSize = minLineSize
+	}
+	if opts.LongLines == "" {
+		opts.LongLines = LongLineTruncate
+	}
+
+	lr := &lineReader{opts: opts, asm: asm, dec: newDecoder(opts.Encoding), start: offset, lineStart: offset}
+	br := bufio.NewReaderSize(r, opts.MaxLineSize)
+	pos := offset
+	for {
+		chunk, err := br.ReadSlice('\n')
+		chunkStart := pos
+		pos += int64(len(chunk))
+		prev := lr.last
+		if len(chunk) > 0 {
+			lr.last = chunk[len(chunk)-1]
+		}
+
+		if chunkStart == 0 {
+			// 文件开头的 BOM 决定编码，本身不参与匹配
+			if enc, n := SniffBOM(chunk); n > 0 {
+				lr.dec = newDecoder(enc)
+				chunk = chunk[n:]
+				lr.start, lr.lineStart = int64(n), int64(n)
+			}
+		}
+
+		switch err {
+		case nil:
+			if lr.dec.wide() {
+				// UTF-16 的换行符占两个字节，0x0A 也可能只是其他字符的一部分
+				lr.append(chunk)
+				end, n, err := lr.wideLineEnd(br, chunk, prev, pos)
+				pos += n
+				switch {
+				case err == io.EOF && !flush:
+					return lr.start, false, nil
+				case err == io.EOF:
+					lr.emit(pos)
+					return pos, false, nil
+				case err != nil:
+					return lr.start, false, fmt.Errorf("读取文件失败: %v", err)
+				case !end:
+					continue
+				}
+			} else {
+				lr.append(chunk[:len(chunk)-1])
+			}
+			lr.emit(pos)
+			if limit > 0 && pos-offset >= limit {
+				return pos, true, nil
+			}
+		case bufio.ErrBufferFull:
+			lr.append(chunk)
+		case io.EOF:
+			if !flush {
+				return lr.start, false, nil
+			}
+			// 末尾没有换行的内容作为最后一行
+			lr.append(chunk)
+			if len(lr.line) > 0 || lr.long {
+				lr.emit(pos)
+			}
+			return pos, false, nil
+		default:
+			// 读取出错（例如压缩文件尚未写完）时不处理末尾不完整的行
+			return lr.start, false, fmt.Errorf("读取文件失败: %v", err)
+		}
+	}
+}
+
+// wideLineEnd 判断 UTF-16 内容中位于 pos-1 的 0x0A 是否为换行符
+// 小端序时换行符的第二个字节在 0x0A 之后，需要再读取一个字节，n 为多读取的字节数
+func (lr *lineReader) wideLineEnd(br *bufio.Reader, chunk []byte, prev byte, pos int64) (bool, int64, error) {
+	at := pos - 1
+	if lr.dec.name == EncodingUTF16BE {
+		if len(chunk) >= 2 {
+			prev = chunk[len(chunk)-2]
+		}
+		return at%2 == 1 && prev == 0, 0, nil
+	}
+	if at%2 != 0 {
+		return false, 0, nil
+	}
+	b, err := br.ReadByte()
+	if err != nil {
+		return false, 0, err
+	}
+	lr.last = b
+	lr.append([]byte{b})
+	return b == 0, 1, nil
+}
+
+// maxLine 单行最大字节数，UTF-16 时取偶数以免拆开编码单元
+func (lr *lineReader) maxLine() int {
+	max := lr.opts.MaxLineSize
+	if lr.dec.wide() && max%2 == 1 {
+		max--
+	}
+	return max
+}
+
+// append 追加当前行的内容，超过最大长度时按策略处理
+func (lr *lineReader) append(data []byte) {
+	max := lr.maxLine()
+	if lr.long && lr.opts.LongLines != LongLineSplit {
+		return
+	}
+	lr.line = append(lr.line, data...)
+	if len(lr.line)-lr.endLen() <= max {
+		return
+	}
+
+	lr.long = true
+	switch lr.opts.LongLines {
+	case LongLineSplit:
+		// 拆分出的部分已经处理，不会再次读取，在第一次拆分时计数
+		if lr.start == lr.lineStart {
+			metrics.LongLines.WithLabelValues(lr.opts.LongLines).Inc()
+		}
+		for len(lr.line)-lr.endLen() > max {
+			end := lr.start + int64(max)
+			lr.asm.Add(lr.dec.decode(lr.line[:max]), lr.start, end)
+			lr.start = end
+			lr.line = append(lr.line[:0], lr.line[max:]...)
+		}
+	case LongLineSkip:
+		lr.line = lr.line[:0]
+	default:
+		lr.line = lr.line[:max]
+	}
+}
+
+// endLen 当前行末尾已读入的换行符（包括 CRLF 中的 \r）的字节数，不计入行的长度
+// UTF-16 的换行符也在行内容中，小端序时可能只读入了换行符的第一个字节
+func (lr *lineReader) endLen() int {
+	line := lr.line
+	nl, cr := []byte("\n"), []byte("\r")
+	switch lr.dec.name {
+	case EncodingUTF16LE:
+		nl, cr = []byte("\n\x00"), []byte("\r\x00")
+	case EncodingUTF16BE:
+		nl, cr = []byte("\x00\n"), []byte("\x00\r")
+	}
+	// 行内容从编码单元的边界开始，换行符也必须位于边界上
+	aligned := func(n int) bool { return !lr.dec.wide() || (len(line)-n)%2 == 0 }
+
+	n := 0
+	switch {
+	case bytes.HasSuffix(line, nl) && aligned(len(nl)):
+		n = len(nl)
+	case lr.dec.name == EncodingUTF16LE && bytes.HasSuffix(line, nl[:1]) && aligned(1):
+		n = 1
+	}
+	if bytes.HasSuffix(line[:len(line)-n], cr) && aligned(n+len(cr)) {
+		n += len(cr)
+	}
+	return n
+}
+
+// emit 当前行结束，end 为行尾（含换行符）之后的文件偏移量
+// 未写完的行可能被多次读取，截断和丢弃的行在结束时才计数
+func (lr *lineReader) emit(end int64) {
+	if lr.long && lr.opts.LongLines != LongLineSplit {
+		metrics.LongLines.WithLabelValues(lr.opts.LongLines).Inc()
+	}
+	skip := lr.long && (lr.opts.LongLines == LongLineSkip ||
+		lr.opts.LongLines == LongLineSplit && len(lr.line) == 0)
+	if !skip {
+		line := lr.dec.decode(lr.line)
+		if lr.dec.wide() {
+			// UTF-16 的换行符解码后才能去掉
+			line = strings.TrimSuffix(line, "\n")
+		}
+		lr.asm.Add(strings.TrimSuffix(line, "\r"), lr.start, end)
+	}
+	lr.line = lr.line[:0]
+	lr.start = end
+	lr.lineStart = end
+	lr.long = false
+}
+
+// ReadFile 读取已写完的文件，压缩文件会被透明解压，此时偏移量为解压后的字节数
+// 出错时返回已处理到的位置
+func ReadFile(filename string, offset int64, opts LineOptions, asm *Assembler) (int64, error) {
+	file, err := Open(filename)
+	if err != nil {
+		return offset, err
+	}
+	defer file.Close()
+
+	// 从中间继续读取时根据开头的 BOM 确定编码
+	head := make([]byte, min(offset, 3))
+	n, err := io.ReadFull(file, head)
+	if err != nil {
+		return offset, fmt.Errorf("读取文件失败: %v", err)
+	}
+	if enc, _ := SniffBOM(head[:n]); enc != "" {
+		opts.Encoding = enc
+	}
+	if err := file.Skip(offset - int64(n)); err != nil {
+		return offset, err
+	}
+
+	return ReadAll(file, offset, opts, asm)
+}
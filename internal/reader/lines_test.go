@@ -0,0 +1,120 @@
+package reader
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"ClamGuardian/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// readAt 从 content 的 offset 处读取，返回各行及结束位置
+// flush 为 true 时使用 ReadAll，oneByte 为 true 时每次只读出一个字节
+func readAt(t *testing.T, content string, offset int64, opts LineOptions, flush, oneByte bool) ([]string, int64) {
+	t.Helper()
+	var lines []string
+	asm := NewAssembler(nil, func(line string, end int64) {
+		lines = append(lines, fmt.Sprintf("%s@%d", line, end))
+	})
+	r := strings.NewReader(content[offset:])
+	var (
+		pos int64
+		err error
+	)
+	switch {
+	case flush:
+		pos, err = ReadAll(r, offset, opts, asm)
+	case oneByte:
+		pos, err = ReadLines(iotest.OneByteReader(r), offset, opts, asm)
+	default:
+		pos, err = ReadLines(r, offset, opts, asm)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lines, pos
+}
+
+func TestReadLinesPartial(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		offset  int64
+		flush   bool
+		want    string
+		pos     int64
+	}{
+		{"held back", "a\nFOU", 0, false, "[a@2]", 2},
+		{"only partial", "FOU", 0, false, "[]", 0},
+		{"completed", "a\nFOUND\n", 2, false, "[FOUND@8]", 8},
+		{"completed with CRLF", "a\r\nFOUND\r\n", 3, false, "[FOUND@10]", 10},
+		{"timed out", "a\nFOU", 2, true, "[FOU@5]", 5},
+		{"timed out then completed", "a\nFOUND\n", 5, false, "[ND@8]", 8},
+		{"flush without partial", "a\n", 0, true, "[a@2]", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, oneByte := range []bool{false, true} {
+				lines, pos := readAt(t, tt.content, tt.offset, LineOptions{MaxLineSize: 64}, tt.flush, oneByte)
+				if fmt.Sprint(lines) != tt.want || pos != tt.pos {
+					t.Errorf("oneByte=%v: 读取到 %v，位置 %d，应为 %s，位置 %d", oneByte, lines, pos, tt.want, tt.pos)
+				}
+			}
+		})
+	}
+}
+
+func TestPartialWait(t *testing.T) {
+	if wait := (LineOptions{}).PartialWait(); wait != defaultPartialTimeout {
+		t.Errorf("未设置时等待 %v，应为 %v", wait, defaultPartialTimeout)
+	}
+	if wait := (LineOptions{PartialTimeout: 2 * time.Second}).PartialWait(); wait != 2*time.Second {
+		t.Errorf("等待 %v，应为 2s", wait)
+	}
+}
+
+func TestReadLinesLongLines(t *testing.T) {
+	const max = minLineSize
+	exact := strings.Repeat("a", max)
+	over := strings.Repeat("b", max) + "c"
+	double := strings.Repeat("d", 2*max)
+
+	tests := []struct {
+		name    string
+		policy  string
+		content string
+		want    []string
+		counted float64
+	}{
+		{"truncate exact", LongLineTruncate, exact + "\n", []string{exact + "@17"}, 0},
+		{"truncate over", LongLineTruncate, over + "\nx\n", []string{over[:max] + "@18", "x@20"}, 1},
+		{"split exact", LongLineSplit, exact + "\n", []string{exact + "@17"}, 0},
+		{"split over", LongLineSplit, over + "\nx\n", []string{over[:max] + "@16", "c@18", "x@20"}, 1},
+		{"split double", LongLineSplit, double + "\nx\n", []string{double[:max] + "@16", double[max:] + "@33", "x@35"}, 1},
+		{"split exact with CRLF", LongLineSplit, exact + "\r\nx\n", []string{exact + "@18", "x@20"}, 0},
+		{"skip exact", LongLineSkip, exact + "\n", []string{exact + "@17"}, 0},
+		{"skip over", LongLineSkip, over + "\nx\n", []string{"x@20"}, 1},
+		{"skip over with CRLF", LongLineSkip, over + "\r\nx\n", []string{"x@21"}, 1},
+		{"truncate exact with CRLF", LongLineTruncate, exact + "\r\n", []string{exact + "@18"}, 0},
+		{"skip exact with CRLF", LongLineSkip, exact + "\r\nx\n", []string{exact + "@18", "x@20"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, oneByte := range []bool{false, true} {
+				counter := metrics.LongLines.WithLabelValues(tt.policy)
+				before := testutil.ToFloat64(counter)
+				opts := LineOptions{MaxLineSize: max, LongLines: tt.policy}
+				lines, pos := readAt(t, tt.content, 0, opts, false, oneByte)
+				if fmt.Sprint(lines) != fmt.Sprint(tt.want) || pos != int64(len(tt.content)) {
+					t.Errorf("oneByte=%v: 读取到 %v，位置 %d，应为 %v，位置 %d", oneByte, lines, pos, tt.want, len(tt.content))
+				}
+				if counted := testutil.ToFloat64(counter) - before; counted != tt.counted {
+					t.Errorf("oneByte=%v: 超长行计数 %v，应为 %v", oneByte, counted, tt.counted)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package reader
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MultilineConfig 多行事件的合并配置
+type MultilineConfig struct {
+	Start        string `mapstructure:"start"`        // 事件起始行的正则，不匹配的行并入上一个事件
+	Continuation string `mapstructure:"continuation"` // 续行的正则，匹配的行并入上一个事件
+	Indent       bool   `mapstructure:"indent"`       // 以空白字符开头的行视为续行
+	Timeout      int    `mapstructure:"timeout"`      // 未结束的事件最长等待时间(秒)
+	MaxLines     int    `mapstructure:"max_lines"`    // 单个事件的最大行数
+}
+
+// Enabled 是否配置了多行合并
+func (c MultilineConfig) Enabled() bool {
+	return c.Start != "" || c.Continuation != "" || c.Indent
+}
+
+// 多行合并的默认值
+const (
+	defaultMultilineTimeout  = 3
+	defaultMultilineMaxLines = 500
+)
+
+// Multiline 编译后的多行合并规则
+type Multiline struct {
+	start        *regexp.Regexp
+	continuation *regexp.Regexp
+	indent       bool
+	timeout      time.Duration
+	maxLines     int
+}
+
+// CompileMultiline 编译多行合并配置，未启用时返回 nil
+func CompileMultiline(cfg MultilineConfig) (*Multiline, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	ml := &Multiline{
+		indent:   cfg.Indent,
+		timeout:  time.Duration(cfg.Timeout) * time.Second,
+		maxLines: cfg.MaxLines,
+	}
+	if ml.timeout <= 0 {
+		ml.timeout = defaultMultilineTimeout * time.Second
+	}
+	if ml.maxLines <= 0 {
+		ml.maxLines = defaultMultilineMaxLines
+	}
+
+	var err error
+	if cfg.Start != "" {
+		if ml.start, err = regexp.Compile(cfg.Start); err != nil {
+			return nil, fmt.Errorf("编译多行起始正则失败 %s: %v", cfg.Start, err)
+		}
+	}
+	if cfg.Continuation != "" {
+		if ml.continuation, err = regexp.Compile(cfg.Continuation); err != nil {
+			return nil, fmt.Errorf("编译多行续行正则失败 %s: %v", cfg.Continuation, err)
+		}
+	}
+	return ml, nil
+}
+
+// isContinuation 判断该行是否属于上一个事件
+func (ml *Multiline) isContinuation(line string) bool {
+	if ml.continuation != nil && ml.continuation.MatchString(line) {
+		return true
+	}
+	if ml.indent && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+		return true
+	}
+	return ml.start != nil && !ml.start.MatchString(line)
+}
+
+// EmitFunc 接收合并后的事件，end 为事件最后一行之后的文件偏移量
+type EmitFunc func(event string, end int64)
+
+// Assembler 将连续的多行合并为一个事件后再交给后续处理
+// 每个文件使用独立的 Assembler，未结束的事件跨多次读取保留
+type Assembler struct {
+	ml      *Multiline
+	emit    EmitFunc
+	lines   []string
+	start   int64 // 未结束事件第一行的文件偏移量
+	end     int64 // 未结束事件最后一行之后的文件偏移量
+	updated time.Time
+}
+
+// NewAssembler 创建多行合并器，ml 为 nil 时每行单独作为一个事件
+func NewAssembler(ml *Multiline, emit EmitFunc) *Assembler {
+	return &Assembler{ml: ml, emit: emit}
+}
+
+// Add 加入一行，start 和 end 为该行起止位置的文件偏移量
+func (a *Assembler) Add(line string, start, end int64) {
+	if a.ml == nil {
+		a.emit(line, end)
+		return
+	}
+
+	if len(a.lines) > 0 && (!a.ml.isContinuation(line) || len(a.lines) >= a.ml.maxLines) {
+		a.Flush()
+	}
+	if len(a.lines) == 0 {
+		a.start = start
+	}
+	a.lines = append(a.lines, line)
+	a.end = end
+	a.updated = time.Now()
+}
+
+// Flush 立即结束当前事件
+func (a *Assembler) Flush() {
+	if len(a.lines) == 0 {
+		return
+	}
+	event := strings.Join(a.lines, "\n")
+	a.lines = a.lines[:0]
+	a.emit(event, a.end)
+}
+
+// Expired 未结束的事件是否已超过等待时间
+func (a *Assembler) Expired(now time.Time) bool {
+	return a.ml != nil && len(a.lines) > 0 && now.Sub(a.updated) >= a.ml.timeout
+}
+
+// Pending 返回未结束事件的起始偏移量
+// 保存读取位置时应使用该偏移量，重启后重新读取尚未匹配的行
+func (a *Assembler) Pending() (int64, bool) {
+	if len(a.lines) == 0 {
+		return 0, false
+	}
+	return a.start, true
+}
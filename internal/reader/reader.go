@@ -0,0 +1,137 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression 压缩格式
+type Compression string
+
+const (
+	None  Compression = ""
+	Gzip  Compression = "gzip"
+	Bzip2 Compression = "bzip2"
+	Zstd  Compression = "zstd"
+)
+
+// 各压缩格式的文件头
+var magics = []struct {
+	magic       []byte
+	compression Compression
+}{
+	{[]byte{0x1f, 0x8b}, Gzip},
+	{[]byte("BZh"), Bzip2},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, Zstd},
+}
+
+// IsCompressed 根据扩展名判断文件是否为压缩归档
+func IsCompressed(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gz", ".bz2", ".zst", ".zstd":
+		return true
+	}
+	return false
+}
+
+// File 打开的日志文件，压缩文件会被透明解压
+type File struct {
+	io.Reader
+	Compression Compression
+
+	file   *os.File
+	br     *bufio.Reader // 读取文件头使用的缓冲，未压缩的文件定位时需要扣除其中未消费的内容
+	closer func()
+}
+
+// Open 打开文件，根据文件头识别压缩格式
+// 压缩扩展名的文件还没有对应的文件头时返回错误，例如 gzip 刚创建、尚未写入内容的文件
+func Open(filename string) (*File, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+
+	f, err := newFile(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if f.Compression == None && IsCompressed(filename) {
+		f.Close()
+		return nil, fmt.Errorf("压缩文件 %s 尚未写入文件头", filename)
+	}
+	return f, nil
+}
+
+// newFile 识别压缩格式并创建对应的解压器
+func newFile(file *os.File) (*File, error) {
+	br := bufio.NewReader(file)
+	head, _ := br.Peek(4)
+
+	f := &File{file: file, Reader: br, br: br}
+	for _, m := range magics {
+		if bytes.HasPrefix(head, m.magic) {
+			f.Compression = m.compression
+			break
+		}
+	}
+
+	switch f.Compression {
+	case Gzip:
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("创建 gzip 解压器失败: %v", err)
+		}
+		f.Reader, f.closer = zr, func() { zr.Close() }
+	case Bzip2:
+		f.Reader = bzip2.NewReader(br)
+	case Zstd:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("创建 zstd 解压器失败: %v", err)
+		}
+		f.Reader, f.closer = zr, zr.Close
+	}
+	return f, nil
+}
+
+// Skip 从当前位置跳过解压后的 n 个字节，未压缩的文件直接定位
+func (f *File) Skip(n int64) error {
+	if n == 0 {
+		return nil
+	}
+	if f.Compression == None {
+		// 缓冲中已读入但未消费的内容仍在当前位置之后
+		if f.br != nil {
+			n -= int64(f.br.Buffered())
+			f.br = nil
+		}
+		if _, err := f.file.Seek(n, io.SeekCurrent); err != nil {
+			return fmt.Errorf("设置文件偏移量失败: %v", err)
+		}
+		f.Reader = f.file
+		return nil
+	}
+	if _, err := io.CopyN(io.Discard, f.Reader, n); err != nil {
+		return fmt.Errorf("跳过已处理内容失败: %v", err)
+	}
+	return nil
+}
+
+// Close 关闭解压器和文件
+func (f *File) Close() error {
+	if f.closer != nil {
+		f.closer()
+	}
+	return f.file.Close()
+}
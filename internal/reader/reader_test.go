@@ -0,0 +1,106 @@
+package reader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// collect 读取文件，返回各行及结束位置
+func collect(t *testing.T, filename string, offset int64, opts LineOptions) ([]string, int64, error) {
+	t.Helper()
+	var lines []string
+	asm := NewAssembler(nil, func(line string, end int64) {
+		lines = append(lines, fmt.Sprintf("%s@%d", line, end))
+	})
+	pos, err := ReadFile(filename, offset, opts, asm)
+	return lines, pos, err
+}
+
+func gzipData(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadFileResume(t *testing.T) {
+	const content = "line1\nline2\nline3\n"
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "clamd.log")
+	compressed := filepath.Join(dir, "clamd.log.1.gz")
+	if err := os.WriteFile(plain, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(compressed, gzipData(t, content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		offset int64
+		want   []string
+	}{
+		{0, []string{"line1@6", "line2@12", "line3@18"}},
+		{1, []string{"ine1@6", "line2@12", "line3@18"}},
+		{3, []string{"e1@6", "line2@12", "line3@18"}},
+		{6, []string{"line2@12", "line3@18"}},
+		{12, []string{"line3@18"}},
+		{18, nil},
+	}
+	for _, filename := range []string{plain, compressed} {
+		for _, tt := range tests {
+			lines, pos, err := collect(t, filename, tt.offset, LineOptions{})
+			if err != nil {
+				t.Fatalf("%s 从 %d 读取失败: %v", filepath.Base(filename), tt.offset, err)
+			}
+			if fmt.Sprint(lines) != fmt.Sprint(tt.want) || pos != int64(len(content)) {
+				t.Errorf("%s 从 %d 读取得到 %q 和位置 %d，应为 %q 和位置 %d",
+					filepath.Base(filename), tt.offset, lines, pos, tt.want, len(content))
+			}
+		}
+	}
+}
+
+func TestReadFileResumeWithBOM(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "clamd.log")
+	// UTF-16LE BOM 之后是 "a\nb\n"
+	data := []byte{0xff, 0xfe, 'a', 0, '\n', 0, 'b', 0, '\n', 0}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	lines, pos, err := collect(t, filename, 6, LineOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(lines) != "[b@10]" || pos != 10 {
+		t.Errorf("从 6 读取得到 %q 和位置 %d", lines, pos)
+	}
+}
+
+func TestReadFileCompressedNotReady(t *testing.T) {
+	dir := t.TempDir()
+	full := gzipData(t, "line1\nline2\n")
+	for name, data := range map[string][]byte{
+		"empty.gz":     nil,
+		"magic.gz":     full[:1],
+		"truncated.gz": full[:len(full)-4],
+		"plain.gz":     []byte("line1\n"),
+	} {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := collect(t, filename, 0, LineOptions{}); err == nil {
+			t.Errorf("%s 应返回错误，等待写入完成", name)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package source
+
+import "context"
+
+// 输入类型
+const (
+	KindFile   = "file"
+	KindStdin  = "stdin"
+	KindSyslog = "syslog"
+)
+
+// Origin 事件的来源信息
+type Origin struct {
+	Input string // 输入名称
+	Kind  string // 输入类型
+	Path  string // 文件路径，非文件输入为空
+	// Backfill 内容来自轮转后或压缩的归档文件，不是正在写入的文件中新出现的内容
+	Backfill bool
+}
+
+// Event 输入源产生的一条事件
+type Event struct {
+	Line   string
+	Origin Origin
+	Fields map[string]string // 来源附带的字段，例如 syslog 头部，可为 nil
+}
+
+// Handler 处理输入源产生的事件
+// Handle 返回即视为事件已处理，文件输入随后才会保存读取位置
+type Handler interface {
+	Handle(ev Event)
+}
+
+// PathCloser 需要知道文件停止跟踪的处理函数实现该接口，例如清理按文件记录的心跳状态
+// 文件被删除或重命名后，文件输入以原路径调用 ClosePath
+type PathCloser interface {
+	ClosePath(path string)
+}
+
+// HandlerFunc 将函数转换为 Handler
+type HandlerFunc func(ev Event)
+
+// Handle 调用 f(ev)
+func (f HandlerFunc) Handle(ev Event) {
+	f(ev)
+}
+
+// Source 输入源，文件、标准输入、套接字等输入都实现该接口
+type Source interface {
+	// Name 配置中的输入名称
+	Name() string
+	// Kind 输入类型
+	Kind() string
+	// Start 开始读取，事件交给 h 处理，ctx 取消后停止
+	Start(ctx context.Context, h Handler) error
+	// Stop 停止读取并释放资源
+	Stop() error
+}
@@ -0,0 +1,73 @@
+package source
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+
+	"ClamGuardian/internal/logger"
+	"ClamGuardian/internal/reader"
+	"go.uber.org/zap"
+)
+
+// Stdin 从标准输入逐行读取的输入源，适合通过管道接入其他程序的输出
+type Stdin struct {
+	name  string
+	r     io.Reader
+	lines reader.LineOptions
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewStdin 创建标准输入输入源
+func NewStdin(name string, lines reader.LineOptions) *Stdin {
+	return &Stdin{
+		name:  name,
+		r:     os.Stdin,
+		lines: lines,
+		done:  make(chan struct{}),
+	}
+}
+
+// Name 输入名称
+func (s *Stdin) Name() string { return s.name }
+
+// Kind 输入类型
+func (s *Stdin) Kind() string { return KindStdin }
+
+// Start 开始读取标准输入
+func (s *Stdin) Start(ctx context.Context, h Handler) error {
+	go s.run(ctx, h)
+	return nil
+}
+
+// run 逐行读取直到输入结束
+// 标准输入无法中断阻塞中的读取，停止后不再处理读取到的内容
+func (s *Stdin) run(ctx context.Context, h Handler) {
+	asm := reader.NewAssembler(nil, func(line string, _ int64) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.done:
+			return
+		default:
+		}
+		h.Handle(Event{
+			Line:   line,
+			Origin: Origin{Input: s.name, Kind: KindStdin},
+		})
+	})
+
+	if _, err := reader.ReadAll(s.r, 0, s.lines, asm); err != nil {
+		logger.Logger.Error("读取标准输入失败", zap.String("input", s.name), zap.Error(err))
+		return
+	}
+	logger.Logger.Info("标准输入已结束", zap.String("input", s.name))
+}
+
+// Stop 停止读取
+func (s *Stdin) Stop() error {
+	s.once.Do(func() { close(s.done) })
+	return nil
+}
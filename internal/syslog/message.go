@@ -0,0 +1,242 @@
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 消息格式
+const (
+	FormatRFC3164 = "rfc3164"
+	FormatRFC5424 = "rfc5424"
+)
+
+// 设施名称，按 RFC 5424 的编号排列
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// 严重级别名称，按 RFC 5424 的编号排列
+var severityNames = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+// Message 解析后的 syslog 消息
+type Message struct {
+	Format    string
+	Priority  int
+	Facility  int
+	Severity  int
+	Timestamp time.Time
+	Hostname  string
+	AppName   string
+	ProcID    string
+	MsgID     string
+	Message   string
+}
+
+// FacilityName 设施名称
+func (m *Message) FacilityName() string {
+	if m.Facility >= 0 && m.Facility < len(facilityNames) {
+		return facilityNames[m.Facility]
+	}
+	return strconv.Itoa(m.Facility)
+}
+
+// SeverityName 严重级别名称
+func (m *Message) SeverityName() string {
+	if m.Severity >= 0 && m.Severity < len(severityNames) {
+		return severityNames[m.Severity]
+	}
+	return strconv.Itoa(m.Severity)
+}
+
+// Fields 返回供规则匹配和告警使用的字段，空值不包含在内
+func (m *Message) Fields() map[string]string {
+	fields := map[string]string{
+		"format":    m.Format,
+		"priority":  strconv.Itoa(m.Priority),
+		"facility":  m.FacilityName(),
+		"severity":  m.SeverityName(),
+		"timestamp": m.Timestamp.Format(time.RFC3339),
+	}
+	for key, value := range map[string]string{
+		"hostname": m.Hostname,
+		"app_name": m.AppName,
+		"proc_id":  m.ProcID,
+		"msg_id":   m.MsgID,
+	} {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// Parse 解析一条 syslog 消息，自动识别 RFC 3164 和 RFC 5424 格式
+func Parse(data []byte) (*Message, error) {
+	data = bytes.TrimRight(data, "\r\n\x00")
+	if len(data) < 3 || data[0] != '<' {
+		return nil, fmt.Errorf("缺少优先级字段")
+	}
+
+	end := bytes.IndexByte(data, '>')
+	if end < 2 || end > 4 {
+		return nil, fmt.Errorf("优先级字段格式错误")
+	}
+	pri, err := strconv.Atoi(string(data[1:end]))
+	if err != nil || pri > 191 {
+		return nil, fmt.Errorf("无效的优先级: %s", data[1:end])
+	}
+
+	msg := &Message{
+		Priority: pri,
+		Facility: pri / 8,
+		Severity: pri % 8,
+	}
+	rest := string(data[end+1:])
+
+	// RFC 5424 在优先级之后紧跟版本号
+	if strings.HasPrefix(rest, "1 ") {
+		if err := parseRFC5424(msg, rest[2:]); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+	parseRFC3164(msg, rest)
+	return msg, nil
+}
+
+// parseRFC5424 解析 RFC 5424 格式的头部和消息
+func parseRFC5424(msg *Message, rest string) error {
+	msg.Format = FormatRFC5424
+
+	var fields [5]string
+	for i := range fields {
+		var ok bool
+		fields[i], rest, ok = strings.Cut(rest, " ")
+		if !ok && i < len(fields)-1 {
+			return fmt.Errorf("RFC 5424 头部字段不完整")
+		}
+	}
+
+	if fields[0] != "-" {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("无效的时间戳 %s: %v", fields[0], err)
+		}
+		msg.Timestamp = ts
+	} else {
+		msg.Timestamp = time.Now()
+	}
+	msg.Hostname = nilValue(fields[1])
+	msg.AppName = nilValue(fields[2])
+	msg.ProcID = nilValue(fields[3])
+	msg.MsgID = nilValue(fields[4])
+
+	rest, err := skipStructuredData(rest)
+	if err != nil {
+		return err
+	}
+	rest = strings.TrimPrefix(rest, " ")
+	msg.Message = strings.TrimPrefix(rest, "\ufeff")
+	return nil
+}
+
+// skipStructuredData 跳过结构化数据部分
+func skipStructuredData(rest string) (string, error) {
+	if rest == "" || rest == "-" {
+		return "", nil
+	}
+	if strings.HasPrefix(rest, "- ") {
+		return rest[2:], nil
+	}
+	if rest[0] != '[' {
+		return "", fmt.Errorf("结构化数据格式错误")
+	}
+
+	inElement, inQuote, escaped := false, false, false
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inQuote && c == '\\':
+			escaped = true
+		case c == '"':
+			inQuote = !inQuote
+		case !inQuote && c == '[':
+			inElement = true
+		case !inQuote && c == ']':
+			inElement = false
+			if i+1 == len(rest) || rest[i+1] != '[' {
+				return rest[i+1:], nil
+			}
+		case !inElement:
+			return rest[i:], nil
+		}
+	}
+	return "", fmt.Errorf("结构化数据未结束")
+}
+
+// nilValue 将 RFC 5424 的空值 "-" 转换为空字符串
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// parseRFC3164 解析 RFC 3164 格式，无法识别的部分整体作为消息内容
+func parseRFC3164(msg *Message, rest string) {
+	msg.Format = FormatRFC3164
+	msg.Timestamp = time.Now()
+
+	// 时间戳固定为 "Mmm dd hh:mm:ss"
+	const stampLen = len(time.Stamp)
+	hasStamp := false
+	if len(rest) > stampLen && rest[stampLen] == ' ' {
+		if ts, err := time.ParseInLocation(time.Stamp, rest[:stampLen], time.Local); err == nil {
+			now := time.Now()
+			ts = ts.AddDate(now.Year(), 0, 0)
+			// 跨年时时间戳可能属于上一年
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			msg.Timestamp = ts
+			rest = rest[stampLen+1:]
+			hasStamp = true
+		}
+	}
+
+	// 本地套接字发送的消息通常省略主机名，第一个字段直接是 TAG
+	// 没有时间戳的消息不包含头部，不从中提取主机名
+	first, remainder, _ := strings.Cut(rest, " ")
+	if hasStamp && !isTag(first) {
+		msg.Hostname = first
+		rest = remainder
+	}
+
+	tag, content, ok := strings.Cut(rest, " ")
+	if !ok || !isTag(tag) {
+		msg.Message = rest
+		return
+	}
+	tag = strings.TrimSuffix(tag, ":")
+	if i := strings.IndexByte(tag, '['); i >= 0 && strings.HasSuffix(tag, "]") {
+		msg.ProcID = tag[i+1 : len(tag)-1]
+		tag = tag[:i]
+	}
+	msg.AppName = tag
+	msg.Message = content
+}
+
+// isTag 判断字段是否为 "app:" 或 "app[pid]:" 形式的 TAG
+func isTag(s string) bool {
+	return len(s) > 1 && strings.HasSuffix(s, ":")
+}
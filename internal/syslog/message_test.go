@@ -0,0 +1,106 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Message // Timestamp 单独检查
+	}{
+		{
+			name: "rfc5424",
+			data: "<165>1 2026-10-16T10:00:00.123Z scan01 clamd 4321 ID47 - Eicar-Signature FOUND",
+			want: Message{Format: FormatRFC5424, Priority: 165, Facility: 20, Severity: 5,
+				Hostname: "scan01", AppName: "clamd", ProcID: "4321", MsgID: "ID47", Message: "Eicar-Signature FOUND"},
+		},
+		{
+			name: "rfc5424 nil values",
+			data: "<14>1 - - - - - -",
+			want: Message{Format: FormatRFC5424, Priority: 14, Facility: 1, Severity: 6},
+		},
+		{
+			name: "rfc5424 structured data",
+			data: `<14>1 2026-10-16T10:00:00Z scan01 clamd - - [ex@32473 a="x \"]\" y"][b@32473 c="d"] ` + "\ufeffhello",
+			want: Message{Format: FormatRFC5424, Priority: 14, Facility: 1, Severity: 6,
+				Hostname: "scan01", AppName: "clamd", Message: "hello"},
+		},
+		{
+			name: "rfc3164",
+			data: "<38>Oct 16 10:00:00 scan01 clamd[4321]: /tmp/eicar.com: Eicar-Signature FOUND\n",
+			want: Message{Format: FormatRFC3164, Priority: 38, Facility: 4, Severity: 6,
+				Hostname: "scan01", AppName: "clamd", ProcID: "4321", Message: "/tmp/eicar.com: Eicar-Signature FOUND"},
+		},
+		{
+			name: "rfc3164 local socket without hostname",
+			data: "<30>Oct  6 10:00:00 freshclam: Database updated",
+			want: Message{Format: FormatRFC3164, Priority: 30, Facility: 3, Severity: 6,
+				AppName: "freshclam", Message: "Database updated"},
+		},
+		{
+			name: "rfc3164 without header",
+			data: "<13>plain message: text",
+			want: Message{Format: FormatRFC3164, Priority: 13, Facility: 1, Severity: 5,
+				Message: "plain message: text"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := Parse([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Parse 失败: %v", err)
+			}
+			if msg.Timestamp.IsZero() {
+				t.Error("未设置时间戳")
+			}
+			got := *msg
+			got.Timestamp = time.Time{}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v，应为 %+v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	msg, err := Parse([]byte("<14>1 2026-10-16T10:00:00.5+08:00 - - - - -"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 10, 16, 2, 0, 0, 5e8, time.UTC); !msg.Timestamp.Equal(want) {
+		t.Errorf("时间戳为 %v，应为 %v", msg.Timestamp, want)
+	}
+
+	msg, err = Parse([]byte("<14>Jan  2 03:04:05 host app: x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Timestamp.Month() != time.January || msg.Timestamp.Day() != 2 || msg.Timestamp.Hour() != 3 {
+		t.Errorf("RFC 3164 时间戳为 %v", msg.Timestamp)
+	}
+	if msg.Timestamp.After(time.Now().Add(24 * time.Hour)) {
+		t.Errorf("RFC 3164 时间戳 %v 晚于当前时间", msg.Timestamp)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"no priority",
+		"<>x",
+		"<1234>x",
+		"<abc>x",
+		"<192>x",
+		"<14>1 2026-10-16T10:00:00Z host",
+		"<14>1 not-a-time host app - - -",
+		"<14>1 - host app - - x",
+		`<14>1 - host app - - [ex@32473 a="b"`,
+	} {
+		if msg, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Parse(%q) = %+v，应返回错误", data, msg)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	"ClamGuardian/internal/logger"
+	"ClamGuardian/internal/metrics"
+	"ClamGuardian/internal/source"
+	"go.uber.org/zap"
+)
+
+// 监听协议
+const (
+	ProtocolUDP      = "udp"
+	ProtocolTCP      = "tcp"
+	ProtocolUnix     = "unix"     // 流式 unix 套接字
+	ProtocolUnixgram = "unixgram" // 数据报 unix 套接字，与 /dev/log 相同
+)
+
+// maxMessageSize 单条消息的最大长度
+const maxMessageSize = 64 * 1024
+
+// ListenerConfig 监听配置
+type ListenerConfig struct {
+	Protocol string `mapstructure:"protocol"` // udp、tcp、unix 或 unixgram
+	Address  string `mapstructure:"address"`  // 监听地址，unix 协议为套接字路径
+}
+
+// Server syslog 接收服务，作为 syslog 类型的输入源
+type Server struct {
+	name      string
+	listeners []ListenerConfig
+	handler   source.Handler
+	closers   map[io.Closer]struct{}
+	stopped   bool // 已调用 Stop，之后接受的连接立即关闭
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+}
+
+// NewServer 创建 syslog 接收服务
+func NewServer(name string, listeners []ListenerConfig) (*Server, error) {
+	for _, l := range listeners {
+		switch l.Protocol {
+		case ProtocolUDP, ProtocolTCP, ProtocolUnix, ProtocolUnixgram:
+		default:
+			return nil, fmt.Errorf("不支持的 syslog 协议: %s", l.Protocol)
+		}
+		if l.Address == "" {
+			return nil, fmt.Errorf("syslog %s 监听未指定地址", l.Protocol)
+		}
+	}
+
+	return &Server{
+		name:      name,
+		listeners: listeners,
+		closers:   make(map[io.Closer]struct{}),
+	}, nil
+}
+
+// Name 输入名称
+func (s *Server) Name() string { return s.name }
+
+// Kind 输入类型
+func (s *Server) Kind() string { return source.KindSyslog }
+
+// Start 开始监听所有地址，解析后的消息交给 h 处理
+func (s *Server) Start(ctx context.Context, h source.Handler) error {
+	s.handler = h
+	for _, l := range s.listeners {
+		if err := s.listen(l); err != nil {
+			s.Stop()
+			return err
+		}
+		logger.Logger.Info("syslog 监听已启动",
+			zap.String("protocol", l.Protocol),
+			zap.String("address", l.Address))
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.Stop()
+	}()
+	return nil
+}
+
+// listen 按协议创建监听
+func (s *Server) listen(l ListenerConfig) error {
+	switch l.Protocol {
+	case ProtocolUDP, ProtocolUnixgram:
+		if l.Protocol == ProtocolUnixgram {
+			os.Remove(l.Address)
+		}
+		conn, err := net.ListenPacket(l.Protocol, l.Address)
+		if err != nil {
+			return fmt.Errorf("syslog 监听失败 %s %s: %v", l.Protocol, l.Address, err)
+		}
+		s.track(conn)
+		s.wg.Add(1)
+		go s.servePacket(l.Protocol, conn)
+	default:
+		if l.Protocol == ProtocolUnix {
+			os.Remove(l.Address)
+		}
+		ln, err := net.Listen(l.Protocol, l.Address)
+		if err != nil {
+			return fmt.Errorf("syslog 监听失败 %s %s: %v", l.Protocol, l.Address, err)
+		}
+		s.track(ln)
+		s.wg.Add(1)
+		go s.serveStream(l.Protocol, ln)
+	}
+	return nil
+}
+
+// track 记录需要在停止时关闭的资源，已停止时直接关闭
+// Stop 关闭监听之前仍可能接受到新连接，这些连接不能遗漏，否则 Stop 会一直等待其结束
+func (s *Server) track(c io.Closer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		c.Close()
+		return
+	}
+	s.closers[c] = struct{}{}
+}
+
+// untrack 连接结束后不再记录
+func (s *Server) untrack(c io.Closer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.closers, c)
+}
+
+// servePacket 处理数据报协议，每个数据报是一条消息
+func (s *Server) servePacket(protocol string, conn net.PacketConn) {
+	defer s.wg.Done()
+
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !isClosed(err) {
+				logger.Logger.Error("接收 syslog 消息失败", zap.Error(err))
+			}
+			return
+		}
+		s.dispatch(protocol, remoteAddr(addr), buf[:n])
+	}
+}
+
+// serveStream 接受流式连接
+func (s *Server) serveStream(protocol string, ln net.Listener) {
+	defer s.wg.Done()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if !isClosed(err) {
+				logger.Logger.Error("接受 syslog 连接失败", zap.Error(err))
+			}
+			return
+		}
+		s.track(conn)
+		s.wg.Add(1)
+		go s.serveConn(protocol, conn)
+	}
+}
+
+// serveConn 读取单个连接上的消息
+// 支持 RFC 6587 的两种分帧方式：以长度开头的八位组计数和以换行分隔
+func (s *Server) serveConn(protocol string, conn net.Conn) {
+	defer s.wg.Done()
+	defer s.untrack(conn)
+	defer conn.Close()
+
+	remote := remoteAddr(conn.RemoteAddr())
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), maxMessageSize+16)
+	scanner.Split(splitFrame)
+	for scanner.Scan() {
+		s.dispatch(protocol, remote, scanner.Bytes())
+	}
+	if err := scanner.Err(); err != nil && !isClosed(err) {
+		logger.Logger.Warn("读取 syslog 连接失败",
+			zap.String("remote", remote),
+			zap.Error(err))
+	}
+}
+
+// splitFrame 拆分流式连接中的消息
+func splitFrame(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	// 八位组计数："长度 消息"
+	if data[0] >= '1' && data[0] <= '9' {
+		if sp := bytes.IndexByte(data, ' '); sp > 0 {
+			if length, err := strconv.Atoi(string(data[:sp])); err == nil {
+				if length > maxMessageSize {
+					return 0, nil, fmt.Errorf("syslog 消息过长: %d", length)
+				}
+				if len(data) < sp+1+length {
+					if atEOF {
+						return len(data), data[sp+1:], nil
+					}
+					return 0, nil, nil
+				}
+				return sp + 1 + length, data[sp+1 : sp+1+length], nil
+			}
+		}
+	}
+
+	// 换行分隔
+	return bufio.ScanLines(data, atEOF)
+}
+
+// dispatch 解析消息并交给处理函数，remote 为发送方地址，未知时为空
+func (s *Server) dispatch(protocol, remote string, data []byte) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return
+	}
+
+	msg, err := Parse(data)
+	if err != nil {
+		metrics.SyslogMessages.WithLabelValues(protocol, "invalid").Inc()
+		logger.Logger.Debug("无法解析 syslog 消息",
+			zap.ByteString("data", data),
+			zap.Error(err))
+		return
+	}
+	metrics.SyslogMessages.WithLabelValues(protocol, msg.Format).Inc()
+	fields := msg.Fields()
+	if remote != "" {
+		fields["remote"] = remote
+	}
+	s.handler.Handle(source.Event{
+		Line:   msg.Message,
+		Origin: source.Origin{Input: s.name, Kind: source.KindSyslog},
+		Fields: fields,
+	})
+}
+
+// remoteAddr 返回发送方地址，未绑定地址的 unix 套接字返回空
+func remoteAddr(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
+
+// Stop 停止监听并关闭所有连接
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	s.stopped = true
+	closers := s.closers
+	s.closers = make(map[io.Closer]struct{})
+	s.mu.Unlock()
+
+	for c := range closers {
+		c.Close()
+	}
+	s.wg.Wait()
+	return nil
+}
+
+// isClosed 判断错误是否由关闭监听引起
+func isClosed(err error) bool {
+	return errors.Is(err, net.ErrClosed)
+}
@@ -0,0 +1,112 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ClamGuardian/internal/source"
+)
+
+func TestSplitFrame(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []string
+	}{
+		{"newline", "<14>a\n<14>b\r\n<14>c", []string{"<14>a", "<14>b", "<14>c"}},
+		{"octet counting", "5 <14>a6 <14>bc", []string{"<14>a", "<14>bc"}},
+		{"octet counting with newline", "6 <14>a\n5 <14>b", []string{"<14>a\n", "<14>b"}},
+		{"mixed", "5 <14>a<14>b\n", []string{"<14>a", "<14>b"}},
+		{"truncated frame", "10 <14>a", []string{"<14>a"}},
+		{"leading digit without length", "1a <14>a\n", []string{"1a <14>a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := bufio.NewScanner(strings.NewReader(tt.stream))
+			scanner.Split(splitFrame)
+			var got []string
+			for scanner.Scan() {
+				got = append(got, scanner.Text())
+			}
+			if err := scanner.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("拆分 %q 得到 %q，应为 %q", tt.stream, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitFrameIncomplete(t *testing.T) {
+	// 不完整的帧等待更多数据
+	for _, data := range []string{"10 <14>a", "<14>a"} {
+		if advance, token, err := splitFrame([]byte(data), false); advance != 0 || token != nil || err != nil {
+			t.Errorf("splitFrame(%q) = %d, %q, %v，应等待更多数据", data, advance, token, err)
+		}
+	}
+
+	data := []byte(strconv.Itoa(maxMessageSize+1) + " <14>a")
+	if _, _, err := splitFrame(data, false); err == nil {
+		t.Error("超过最大长度的消息应返回错误")
+	}
+}
+
+func TestStopWithConnectingClients(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "syslog.sock")
+	s, err := NewServer("syslog", []ListenerConfig{{Protocol: ProtocolUnix, Address: addr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(context.Background(), source.HandlerFunc(func(source.Event) {})); err != nil {
+		t.Fatal(err)
+	}
+
+	// 客户端不断建立连接且不主动关闭，Stop 期间接受的连接也必须被关闭
+	done := make(chan struct{})
+	var clients sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		clients.Add(1)
+		go func() {
+			defer clients.Done()
+			var conns []net.Conn
+			defer func() {
+				for _, c := range conns {
+					c.Close()
+				}
+			}()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if c, err := net.Dial("unix", addr); err == nil {
+					conns = append(conns, c)
+				}
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Error("Stop 等待连接结束超时")
+	}
+	close(done)
+	clients.Wait()
+}